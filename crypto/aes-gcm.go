@@ -41,7 +41,7 @@ func Encrypt(text string) (string, error) {
 	// generates a new aes cipher using our 32 byte long key
 	block, err := aes.NewCipher(Key)
 	if err != nil {
-		return "", errors.Wrapf(err, "[%v] function aes.NewCipher(Key)", errors.Trace())
+		return "", errors.Wrapf(err, "function aes.NewCipher(Key)")
 	}
 
 	// gcm or Galois/Counter Mode, is a mode of operation
@@ -49,7 +49,7 @@ func Encrypt(text string) (string, error) {
 	// - https://en.wikipedia.org/wiki/Galois/Counter_Mode
 	aesgcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", errors.Wrapf(err, "[%v] function cipher.NewGCM(block)", errors.Trace())
+		return "", errors.Wrapf(err, "function cipher.NewGCM(block)")
 	}
 
 	// Never use more than 2^32 random nonces with a given key because of the risk of a repeat.
@@ -60,7 +60,7 @@ func Encrypt(text string) (string, error) {
 	// populates our nonce with a cryptographically secure
 	// random sequence
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", errors.Wrapf(err, "[%v] function io.ReadFull(rand.Reader, nonce)", errors.Trace())
+		return "", errors.Wrapf(err, "function io.ReadFull(rand.Reader, nonce)")
 	}
 
 	// encrypts the text using the Seal function
@@ -84,17 +84,17 @@ func Decrypt(cryptoText string) (string, error) {
 
 	ciphertext, err := base64.URLEncoding.DecodeString(cryptoText)
 	if err != nil {
-		return "", errors.Wrapf(err, "[%v] function base64.URLEncoding.DecodeString(cryptoText)", errors.Trace())
+		return "", errors.Wrapf(err, "function base64.URLEncoding.DecodeString(cryptoText)")
 	}
 
 	block, err := aes.NewCipher(Key)
 	if err != nil {
-		return "", errors.Wrapf(err, "[%v] function aes.NewCipher(Key)", errors.Trace())
+		return "", errors.Wrapf(err, "function aes.NewCipher(Key)")
 	}
 
 	aesgcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", errors.Wrapf(err, "[%v] function cipher.NewGCM(block)", errors.Trace())
+		return "", errors.Wrapf(err, "function cipher.NewGCM(block)")
 	}
 
 	nonceSize := aesgcm.NonceSize()
@@ -106,7 +106,7 @@ func Decrypt(cryptoText string) (string, error) {
 
 	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
-		return "", errors.Wrapf(err, "[%v] function aesgcm.Open()", errors.Trace())
+		return "", errors.Wrapf(err, "function aesgcm.Open()")
 	}
 
 	// fmt.Printf("%s\n", plaintext)
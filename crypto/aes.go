@@ -36,7 +36,7 @@ func EncryptCFB(text string) (string, error) {
 
 	block, err := aes.NewCipher(Key)
 	if err != nil {
-		return "", errors.Wrapf(err, "[%v] function aes.NewCipher(Key)", errors.Trace())
+		return "", errors.Wrapf(err, "function aes.NewCipher(Key)")
 	}
 
 	// The IV needs to be unique, but not secure. Therefore it's common to
@@ -44,7 +44,7 @@ func EncryptCFB(text string) (string, error) {
 	ciphertext := make([]byte, aes.BlockSize+len(plaintext))
 	iv := ciphertext[:aes.BlockSize]
 	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		return "", errors.Wrapf(err, "[%v] function io.ReadFull(rand.Reader, iv)", errors.Trace())
+		return "", errors.Wrapf(err, "function io.ReadFull(rand.Reader, iv)")
 	}
 
 	stream := cipher.NewCFBEncrypter(block, iv)
@@ -64,13 +64,13 @@ func DecryptCFB(cryptoText string) (string, error) {
 
 	block, err := aes.NewCipher(Key)
 	if err != nil {
-		return "", errors.Wrapf(err, "[%v] function aes.NewCipher(key)", errors.Trace())
+		return "", errors.Wrapf(err, "function aes.NewCipher(key)")
 	}
 
 	// The IV needs to be unique, but not secure. Therefore it's common to
 	// include it at the beginning of the ciphertext.
 	if len(ciphertext) < aes.BlockSize {
-		return "", errors.Errorf("[%v] ciphertext too short", errors.Trace())
+		return "", errors.New("ciphertext too short")
 	}
 	iv := ciphertext[:aes.BlockSize]
 	ciphertext = ciphertext[aes.BlockSize:]
@@ -17,6 +17,7 @@ package elasticsearch
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
@@ -33,6 +34,14 @@ import (
 	"x6a.dev/pkg/errors"
 )
 
+var connectRetryOptions = func() errors.RetryOptions {
+	opts := errors.ConnectRetryOptions
+	opts.OnRetry = func(err error, wait time.Duration) {
+		log.Printf("WARNING: unable to connect to elasticsearch db, retrying in %v..", wait.Round(time.Millisecond))
+	}
+	return opts
+}()
+
 var ESClient *elasticsearch.Client
 var Close = make(chan struct{})
 
@@ -48,11 +57,11 @@ func ESConnect(esURL, esUsername, esPassword, esCACertB64 string) (*elasticsearc
 	if len(esCACertB64) > 0 {
 		blob, err := base64.URLEncoding.DecodeString(esCACertB64)
 		if err != nil {
-			return nil, errors.Wrapf(err, "[%v] function base64.URLEncoding.DecodeString(esCACertB64)", errors.Trace())
+			return nil, errors.Wrapf(err, "function base64.URLEncoding.DecodeString(esCACertB64)")
 		}
 		certs = x509.NewCertPool()
 		if ok := certs.AppendCertsFromPEM(blob); !ok {
-			return nil, errors.Wrapf(err, "[%v] function certs.AppendCertsFromPEM(blob)", errors.Trace())
+			return nil, errors.Wrapf(err, "function certs.AppendCertsFromPEM(blob)")
 		}
 	}
 
@@ -74,14 +83,14 @@ func ESConnect(esURL, esUsername, esPassword, esCACertB64 string) (*elasticsearc
 		},
 	}
 
-	es, err := elasticsearch.NewClient(esCfg)
-	for i := 0; err != nil && i < 10; i++ {
-		log.Println("WARNING: unable to connect to elasticsearch db, retrying in 3s..")
-		time.Sleep(3 * time.Second)
+	var es *elasticsearch.Client
+	err := errors.Retry(context.Background(), &connectRetryOptions, func() error {
+		var err error
 		es, err = elasticsearch.NewClient(esCfg)
-	}
+		return err
+	})
 	if err != nil {
-		return nil, errors.Wrapf(err, "[%v] function elasticsearch.NewClient(esCfg): unable to connect to elasticsearch db", errors.Trace())
+		return nil, errors.Wrapf(err, "function elasticsearch.NewClient(esCfg): unable to connect to elasticsearch db")
 	}
 
 	return es, nil
@@ -106,17 +115,18 @@ func ESGet(es *elasticsearch.Client, index, objID string, srcIncludes ...string)
 		)
 	}
 	if err != nil {
-		return nil, errors.Wrapf(err, "[%v] function es.Get()", errors.Trace())
+		return nil, errors.Wrapf(err, "function es.Get()")
 	}
 	defer resp.Body.Close()
 
 	if resp.IsError() {
-		return nil, errors.Errorf("[%s] Error getting document ID=%s", resp.Status(), objID)
+		err := errors.Errorf("[%s] Error getting document ID=%s", resp.Status(), objID)
+		return nil, errors.Typed(err, errors.SetStatusCode(resp.StatusCode))
 	}
 
 	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1048576))
 	if err != nil {
-		return nil, errors.Wrapf(err, "[%v] function ioutil.ReadAll()", errors.Trace())
+		return nil, errors.Wrapf(err, "function ioutil.ReadAll()")
 	}
 
 	return body, nil
@@ -130,12 +140,13 @@ func ESIndex(es *elasticsearch.Client, index, objID string, obj []byte) error {
 		es.Index.WithPretty(),
 	)
 	if err != nil {
-		return errors.Wrapf(err, "[%v] function es.Index()", errors.Trace())
+		return errors.Wrapf(err, "function es.Index()")
 	}
 	defer resp.Body.Close()
 
 	if resp.IsError() {
-		return errors.Errorf("[%s] Error indexing document ID=%s", resp.Status(), objID)
+		err := errors.Errorf("[%s] Error indexing document ID=%s", resp.Status(), objID)
+		return errors.Typed(err, errors.SetStatusCode(resp.StatusCode))
 	}
 
 	return nil
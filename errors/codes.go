@@ -0,0 +1,257 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// DefaultLanguage is the language of the messages written to the logs, and
+// the fallback when none of the requested languages is available.
+var DefaultLanguage = "en"
+
+// CodeSpec declares a stable, machine-readable error code (e.g.
+// "AUTH_TOKEN_EXPIRED") with its default classification and its message
+// templates by language. Templates use the text/template syntax and are
+// executed with the params given to SetCode.
+type CodeSpec struct {
+	Code       string
+	Type       string
+	StatusCode int
+	Priority   Priority
+	Severity   Severity
+	Messages   map[string]string // language tag -> message template
+}
+
+type registeredCode struct {
+	spec      CodeSpec
+	templates map[string]*template.Template
+}
+
+var (
+	errorCodesMu sync.RWMutex
+	errorCodes   = map[string]*registeredCode{}
+)
+
+// RegisterCode declares c in the code registry, replacing any previous
+// declaration of the same code.
+func RegisterCode(c CodeSpec) error {
+	if len(c.Code) == 0 {
+		return New("empty error code")
+	}
+
+	rc := &registeredCode{
+		spec:      c,
+		templates: make(map[string]*template.Template, len(c.Messages)),
+	}
+	for lang, msg := range c.Messages {
+		tmpl, err := template.New(c.Code + "/" + lang).Parse(msg)
+		if err != nil {
+			return Wrapf(err, "function template.Parse(): code %v, language %v", c.Code, lang)
+		}
+		rc.templates[strings.ToLower(lang)] = tmpl
+	}
+
+	errorCodesMu.Lock()
+	defer errorCodesMu.Unlock()
+
+	errorCodes[c.Code] = rc
+
+	return nil
+}
+
+func getCode(code string) (*registeredCode, bool) {
+	errorCodesMu.RLock()
+	defer errorCodesMu.RUnlock()
+
+	rc, ok := errorCodes[code]
+	return rc, ok
+}
+
+// codeParams holds the data the message templates of a code are executed
+// with.
+type codeParams struct {
+	values map[string]interface{}
+}
+
+func (p *codeParams) data() map[string]interface{} {
+	if p == nil {
+		return nil
+	}
+
+	return p.values
+}
+
+type codeOptions struct {
+	code   string
+	params map[string]interface{}
+}
+
+// SetCode sets the error code, along with the Type, StatusCode, Priority and
+// Severity declared for it in the registry. Options given after SetCode
+// override the declared defaults. The params are the data the message
+// templates are executed with.
+func SetCode(code string, params map[string]interface{}) ErrOption {
+	return ErrOption{
+		Key: OptionCode,
+		Value: &codeOptions{
+			code:   code,
+			params: params,
+		},
+	}
+}
+
+// SetAcceptLanguage sets the Accept-Language header value used to pick the
+// language of the message written to the SetHTTPResponse writer.
+func SetAcceptLanguage(acceptLanguage string) ErrOption {
+	return ErrOption{
+		Key:   OptionAcceptLanguage,
+		Value: acceptLanguage,
+	}
+}
+
+// CodeOf returns the error code of the first TypedError in err's chain.
+func CodeOf(err error) string {
+	spec, _ := SpecOf(err)
+
+	return spec.Code
+}
+
+func (s *ErrorSpec) setCode(opt *codeOptions) {
+	s.Code = opt.code
+	s.params = nil
+	if opt.params != nil {
+		s.params = &codeParams{values: opt.params}
+	}
+
+	rc, ok := getCode(opt.code)
+	if !ok {
+		return
+	}
+
+	s.inherit(ErrorSpec{
+		Type:       rc.spec.Type,
+		StatusCode: rc.spec.StatusCode,
+		Priority:   rc.spec.Priority,
+		Severity:   rc.spec.Severity,
+	})
+}
+
+// LocalizedMessage returns the message of the spec's code in the best
+// language of the acceptLanguage list (an Accept-Language header value),
+// falling back to DefaultLanguage. The spec's Message is returned if the code
+// has no message for any of them.
+func (s ErrorSpec) LocalizedMessage(acceptLanguage string) string {
+	for _, lang := range parseAcceptLanguage(acceptLanguage) {
+		if msg, ok := s.localize(lang); ok {
+			return msg
+		}
+	}
+
+	return s.localizedMessage(DefaultLanguage)
+}
+
+func (s ErrorSpec) localizedMessage(lang string) string {
+	if msg, ok := s.localize(lang); ok {
+		return msg
+	}
+
+	return s.Message
+}
+
+func (s ErrorSpec) localize(lang string) (string, bool) {
+	if len(s.Code) == 0 {
+		return "", false
+	}
+
+	rc, ok := getCode(s.Code)
+	if !ok {
+		return "", false
+	}
+
+	lang = strings.ToLower(lang)
+	tmpl, ok := rc.templates[lang]
+	if !ok {
+		// Fall back to the primary language subtag (e.g. "es-ES" -> "es").
+		if i := strings.Index(lang, "-"); i > 0 {
+			tmpl, ok = rc.templates[lang[:i]]
+		}
+	}
+	if !ok {
+		return "", false
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, s.params.data()); err != nil {
+		return "", false
+	}
+
+	return buf.String(), true
+}
+
+// parseAcceptLanguage returns the language tags of an Accept-Language header
+// value ordered by quality.
+func parseAcceptLanguage(acceptLanguage string) []string {
+	type weightedLang struct {
+		lang string
+		q    float64
+	}
+
+	var langs []weightedLang
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang := strings.TrimSpace(fields[0])
+		if len(lang) == 0 || lang == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			langs = append(langs, weightedLang{lang: lang, q: q})
+		}
+	}
+
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+
+	tags := make([]string, len(langs))
+	for i, l := range langs {
+		tags[i] = l.lang
+	}
+
+	return tags
+}
+
+// AcceptLanguage returns the Accept-Language header of r, to be used with
+// SetAcceptLanguage.
+func AcceptLanguage(r *http.Request) string {
+	return r.Header.Get("Accept-Language")
+}
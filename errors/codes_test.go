@@ -0,0 +1,87 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"x6a.dev/pkg/errors"
+	"x6a.dev/pkg/errors/errorstest"
+)
+
+func registerTestCode(t *testing.T) {
+	err := errors.RegisterCode(errors.CodeSpec{
+		Code:       "TEST_USER_NOT_FOUND",
+		Type:       errors.TypeNotFound,
+		StatusCode: http.StatusNotFound,
+		Severity:   errors.SeverityWarning,
+		Messages: map[string]string{
+			"en": "user {{.user}} not found",
+			"es": "usuario {{.user}} no encontrado",
+		},
+	})
+	if err != nil {
+		t.Fatalf("RegisterCode() = %v", err)
+	}
+}
+
+func TestSetCode(t *testing.T) {
+	registerTestCode(t)
+
+	err := errors.Typed(errors.New("no rows"), errors.SetCode("TEST_USER_NOT_FOUND", map[string]interface{}{"user": "alice"}))
+
+	errorstest.AssertType(t, err, errors.TypeNotFound)
+	errorstest.AssertStatusCode(t, err, http.StatusNotFound)
+	errorstest.AssertSeverity(t, err, errors.SeverityWarning)
+
+	spec, _ := errors.SpecOf(err)
+	if spec.Message != "user alice not found" {
+		t.Errorf("got message %q, want the English code message", spec.Message)
+	}
+	if got := spec.LocalizedMessage("fr, es-ES;q=0.8"); got != "usuario alice no encontrado" {
+		t.Errorf("got localized message %q, want the Spanish one", got)
+	}
+}
+
+func TestErrorSpecComparable(t *testing.T) {
+	registerTestCode(t)
+
+	spec, _ := errors.SpecOf(errors.Typed(errors.New("no rows"), errors.SetCode("TEST_USER_NOT_FOUND", map[string]interface{}{"user": "alice"})))
+
+	// ErrorSpec must stay usable with == and as a map key.
+	specs := map[errors.ErrorSpec]bool{spec: true}
+	if !specs[spec] || spec != spec {
+		t.Error("got a spec not equal to itself")
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                            "",
+		"es":                          "es",
+		"fr;q=0.5, es-ES, en;q=0.8":   "es-ES en fr",
+		"en;q=0, es":                  "es",
+		"*, de;q=0.9":                 "de",
+		" pt-BR ; q=0.7 ,it;q=0.7,ja": "ja pt-BR it",
+		"en;q=invalid, es;q=0.5":      "en es",
+	} {
+		if got := strings.Join(errors.ParseAcceptLanguage(in), " "); got != want {
+			t.Errorf("parseAcceptLanguage(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors
+
+import (
+	"context"
+)
+
+type contextKey int
+
+const (
+	requestIDContextKey contextKey = iota
+	userIDContextKey
+	tenantContextKey
+)
+
+// TraceExtractor, if not nil, returns the trace and span IDs of the span in
+// ctx. Set it to bridge a tracing library, e.g. for OpenTelemetry:
+//
+//	errors.TraceExtractor = func(ctx context.Context) (string, string) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		if !sc.IsValid() {
+//			return "", ""
+//		}
+//		return sc.TraceID().String(), sc.SpanID().String()
+//	}
+var TraceExtractor func(ctx context.Context) (traceID, spanID string)
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestID)
+}
+
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDContextKey, userID)
+}
+
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey, tenant)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+func UserIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(userIDContextKey).(string)
+	return id
+}
+
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantContextKey).(string)
+	return tenant
+}
+
+// SetContext attaches the request ID, user ID, tenant and trace/span IDs
+// found in ctx to the ErrorSpec.
+func SetContext(ctx context.Context) ErrOption {
+	return ErrOption{
+		Key:   OptionContext,
+		Value: ctx,
+	}
+}
+
+// LogContext is Log with the correlation identifiers found in ctx attached,
+// see SetContext.
+func LogContext(ctx context.Context, err error, errOpts ...ErrOption) *Error {
+	return Log(err, append([]ErrOption{SetContext(ctx)}, errOpts...)...)
+}
+
+// TypedContext is Typed with the correlation identifiers found in ctx
+// attached, see SetContext.
+func TypedContext(ctx context.Context, err error, errOpts ...ErrOption) error {
+	return Typed(err, append([]ErrOption{SetContext(ctx)}, errOpts...)...)
+}
+
+func (s *ErrorSpec) setContext(ctx context.Context) {
+	if ctx == nil {
+		return
+	}
+
+	if id := RequestIDFromContext(ctx); len(id) > 0 {
+		s.RequestID = id
+	}
+	if id := UserIDFromContext(ctx); len(id) > 0 {
+		s.UserID = id
+	}
+	if tenant := TenantFromContext(ctx); len(tenant) > 0 {
+		s.Tenant = tenant
+	}
+	if TraceExtractor != nil {
+		if traceID, spanID := TraceExtractor(ctx); len(traceID) > 0 {
+			s.TraceID = traceID
+			s.SpanID = spanID
+		}
+	}
+}
+
+// ContextFields returns the non-empty correlation identifiers of the spec,
+// keyed by their JSON names, for reporters and notifiers.
+func (s ErrorSpec) ContextFields() map[string]string {
+	fields := make(map[string]string)
+
+	if len(s.RequestID) > 0 {
+		fields["requestId"] = s.RequestID
+	}
+	if len(s.UserID) > 0 {
+		fields["userId"] = s.UserID
+	}
+	if len(s.Tenant) > 0 {
+		fields["tenant"] = s.Tenant
+	}
+	if len(s.TraceID) > 0 {
+		fields["traceId"] = s.TraceID
+	}
+	if len(s.SpanID) > 0 {
+		fields["spanId"] = s.SpanID
+	}
+
+	return fields
+}
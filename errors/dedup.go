@@ -0,0 +1,122 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type dedupEntry struct {
+	last  *Error
+	count int
+}
+
+var (
+	dedupMu      sync.Mutex
+	dedupWindows = map[Severity]time.Duration{}
+	dedupEntries = map[string]*dedupEntry{}
+)
+
+// SetDedupWindow enables the deduplication of the errors logged with
+// severity s: the first occurrence of an error is reported and the identical
+// ones (see Fingerprint) logged within the window are suppressed, then a
+// "repeated N times" summary is reported at the end of the window. A zero
+// window disables the deduplication, which is the default. Fatal and panic
+// errors are never deduplicated.
+func SetDedupWindow(s Severity, window time.Duration) {
+	dedupMu.Lock()
+	defer dedupMu.Unlock()
+
+	if window <= 0 {
+		delete(dedupWindows, s)
+		return
+	}
+	dedupWindows[s] = window
+}
+
+// Fingerprint identifies identical errors by their type, root cause message
+// and call site.
+func (e *Error) Fingerprint() string {
+	h := sha1.New()
+
+	h.Write([]byte(e.Error.Type))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.Itoa(e.Error.StatusCode)))
+	h.Write([]byte{0})
+	if e.err != nil {
+		h.Write([]byte(Cause(e.err).Error()))
+		if stack := StackOf(e.err); len(stack) > 0 {
+			h.Write([]byte{0})
+			h.Write([]byte(stack[0].File + ":" + strconv.Itoa(stack[0].Line)))
+		}
+	} else {
+		h.Write([]byte(e.Error.Message))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// dedup reports whether e is a repetition to be suppressed.
+func (e *Error) dedup() bool {
+	if e.Error.Severity == SeverityFatal || e.Error.Severity == SeverityPanic {
+		return false
+	}
+
+	dedupMu.Lock()
+	defer dedupMu.Unlock()
+
+	window, ok := dedupWindows[e.Error.Severity]
+	if !ok {
+		return false
+	}
+
+	fp := e.Fingerprint()
+	if entry, ok := dedupEntries[fp]; ok {
+		entry.last = e
+		entry.count++
+		return true
+	}
+
+	dedupEntries[fp] = &dedupEntry{last: e}
+	time.AfterFunc(window, func() {
+		reportRepeated(fp, window)
+	})
+
+	return false
+}
+
+func reportRepeated(fp string, window time.Duration) {
+	dedupMu.Lock()
+	entry := dedupEntries[fp]
+	delete(dedupEntries, fp)
+	dedupMu.Unlock()
+
+	if entry == nil || entry.count == 0 {
+		return
+	}
+
+	summary := *entry.last
+	summary.HTTPWriter = nil
+	summary.Error.Message = fmt.Sprintf("%s (repeated %d times in the last %v)", summary.Error.Message, entry.count, window)
+
+	summary.logError()
+	summary.notify()
+}
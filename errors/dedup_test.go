@@ -0,0 +1,66 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"x6a.dev/pkg/errors"
+	"x6a.dev/pkg/errors/errorstest"
+)
+
+func TestDedupWindow(t *testing.T) {
+	rec := errorstest.Record()
+	defer rec.Close()
+
+	errors.SetDedupWindow(errors.SeverityWarning, 50*time.Millisecond)
+	defer errors.SetDedupWindow(errors.SeverityWarning, 0)
+
+	err := errors.New("connection refused")
+	for i := 0; i < 3; i++ {
+		errors.Log(err, errors.SetSeverity(errors.SeverityWarning), errors.SetOutput(ioutil.Discard))
+	}
+	// Other severities aren't deduplicated.
+	for i := 0; i < 2; i++ {
+		errors.Log(err, errors.SetSeverity(errors.SeverityError), errors.SetOutput(ioutil.Discard))
+	}
+
+	if rec.Len() != 3 {
+		t.Fatalf("got %d reported errors, want the first warning and both errors", rec.Len())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for rec.Len() < 4 {
+		if time.Now().After(deadline) {
+			t.Fatal("no summary reported at the end of the window")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	summary, _ := rec.Last()
+	if !strings.Contains(summary.Message, "repeated 2 times") {
+		t.Errorf("got summary %q, want it repeated 2 times", summary.Message)
+	}
+
+	// A new window starts after the summary.
+	errors.Log(err, errors.SetSeverity(errors.SeverityWarning), errors.SetOutput(ioutil.Discard))
+	if rec.Len() != 5 {
+		t.Errorf("got %d reported errors, want the warning of the new window", rec.Len())
+	}
+}
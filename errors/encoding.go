@@ -0,0 +1,88 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+// Encoding is the output encoding of the default reporter.
+type Encoding string
+
+const (
+	// EncodingText is the human readable text output, colored if the
+	// output is a terminal.
+	EncodingText Encoding = "text"
+	// EncodingLogfmt is the logfmt key=value output, never colored.
+	EncodingLogfmt Encoding = "logfmt"
+	// EncodingJSON is a JSON object per line, with the ErrorSpec fields
+	// along with the time and level.
+	EncodingJSON Encoding = "json"
+)
+
+// EncodingEnv is the environment variable selecting the default encoding
+// (text, logfmt or json).
+const EncodingEnv = "ERRORS_LOG_ENCODING"
+
+var (
+	encodingMu      sync.RWMutex
+	defaultEncoding = encodingFromEnv()
+)
+
+func encodingFromEnv() Encoding {
+	switch Encoding(strings.ToLower(os.Getenv(EncodingEnv))) {
+	case EncodingJSON:
+		return EncodingJSON
+	case EncodingLogfmt:
+		return EncodingLogfmt
+	}
+
+	return EncodingText
+}
+
+// SetDefaultEncoding sets the encoding used by Log when no SetEncoding
+// option is given, overriding the EncodingEnv environment variable.
+func SetDefaultEncoding(enc Encoding) {
+	encodingMu.Lock()
+	defer encodingMu.Unlock()
+
+	defaultEncoding = enc
+}
+
+// DefaultEncoding returns the encoding used by Log when no SetEncoding option
+// is given.
+func DefaultEncoding() Encoding {
+	encodingMu.RLock()
+	defer encodingMu.RUnlock()
+
+	return defaultEncoding
+}
+
+// SetEncoding sets the output encoding for a single Log call. It only applies
+// to the LogrusReporter without a custom Logger.
+func SetEncoding(enc Encoding) ErrOption {
+	return ErrOption{
+		Key:   OptionEncoding,
+		Value: enc,
+	}
+}
+
+// Encoding returns the output encoding requested for e.
+func (e *Error) Encoding() Encoding {
+	return e.encoding
+}
@@ -16,14 +16,13 @@
 package errors
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 
 	"github.com/pkg/errors"
-	"github.com/sirupsen/logrus"
 )
 
 const (
@@ -34,6 +33,12 @@ const (
 	OptionNotifyTo
 	OptionOutput
 	OptionHTTPResponse
+	OptionReporter
+	OptionProblemJSON
+	OptionCode
+	OptionAcceptLanguage
+	OptionContext
+	OptionEncoding
 
 	TypeOK                 = "OK"
 	TypeInternalError      = "InternalError"
@@ -41,6 +46,15 @@ const (
 	TypeSecurity           = "Security"
 	TypeInvalidData        = "InvalidData"
 	TypeNetworkUnreachable = "NetworkUnreachable"
+	TypeNotFound           = "NotFound"
+	TypeConflict           = "Conflict"
+	TypeUnauthorized       = "Unauthorized"
+	TypeForbidden          = "Forbidden"
+	TypeRateLimited        = "RateLimited"
+	TypeTimeout            = "Timeout"
+	TypeUnavailable        = "Unavailable"
+	TypeCanceled           = "Canceled"
+	TypeUnimplemented      = "Unimplemented"
 
 	PriorityLow    Priority = "LOW"
 	PriorityMedium Priority = "MEDIUM"
@@ -61,12 +75,23 @@ type Priority string
 type Severity string
 
 type ErrorSpec struct {
+	Code       string   `json:"code,omitempty"`
 	Message    string   `json:"message,omitempty"`
 	Trace      string   `json:"trace,omitempty"`
 	Type       string   `json:"type,omitempty"` // security | invalidData | networkUnreachable
 	StatusCode int      `json:"statusCode,omitempty"`
 	Priority   Priority `json:"priority,omitempty"`
 	Severity   Severity `json:"severity,omitempty"`
+
+	// Correlation identifiers taken from the context, see SetContext.
+	RequestID string `json:"requestId,omitempty"`
+	UserID    string `json:"userId,omitempty"`
+	Tenant    string `json:"tenant,omitempty"`
+	TraceID   string `json:"traceId,omitempty"`
+	SpanID    string `json:"spanId,omitempty"`
+
+	// params is a pointer so ErrorSpec stays comparable.
+	params *codeParams
 }
 
 type Error struct {
@@ -74,6 +99,12 @@ type Error struct {
 	NotifyTo     []string            `json:"notifyTo,omitempty"`
 	OutputWriter io.Writer           `json:"-"`
 	HTTPWriter   http.ResponseWriter `json:"-"`
+
+	err            error
+	reporter       Reporter
+	problem        *problemOptions
+	acceptLanguage string
+	encoding       Encoding
 }
 
 type ErrOption struct {
@@ -130,160 +161,184 @@ func SetHTTPResponse(w http.ResponseWriter) ErrOption {
 	}
 }
 
-func (e *Error) setOptions(errOpts ...ErrOption) {
+// SetReporter overrides the package default Reporter for a single Log call.
+func SetReporter(r Reporter) ErrOption {
+	return ErrOption{
+		Key:   OptionReporter,
+		Value: r,
+	}
+}
+
+func (s *ErrorSpec) setOptions(errOpts ...ErrOption) {
 	for _, opt := range errOpts {
 		switch opt.Key {
 		case OptionType:
-			e.Error.Type = opt.Value.(string)
+			s.Type = opt.Value.(string)
 		case OptionStatusCode:
-			e.Error.StatusCode = opt.Value.(int)
+			s.StatusCode = opt.Value.(int)
 		case OptionPriority:
-			e.Error.Priority = opt.Value.(Priority)
+			s.Priority = opt.Value.(Priority)
 		case OptionSeverity:
-			e.Error.Severity = opt.Value.(Severity)
+			s.Severity = opt.Value.(Severity)
+		case OptionCode:
+			s.setCode(opt.Value.(*codeOptions))
+		case OptionContext:
+			s.setContext(opt.Value.(context.Context))
+		}
+	}
+}
+
+func (e *Error) setOptions(errOpts ...ErrOption) {
+	e.Error.setOptions(errOpts...)
+
+	for _, opt := range errOpts {
+		switch opt.Key {
 		case OptionNotifyTo:
-			e.NotifyTo = append(e.NotifyTo, opt.Value.(string))
+			e.NotifyTo = append(e.NotifyTo, opt.Value.([]string)...)
 		case OptionOutput:
 			e.OutputWriter = opt.Value.(io.Writer)
 		case OptionHTTPResponse:
 			e.HTTPWriter = opt.Value.(http.ResponseWriter)
+		case OptionReporter:
+			e.reporter = opt.Value.(Reporter)
+		case OptionProblemJSON:
+			e.problem = opt.Value.(*problemOptions)
+		case OptionAcceptLanguage:
+			e.acceptLanguage = opt.Value.(string)
+		case OptionEncoding:
+			e.encoding = opt.Value.(Encoding)
 		}
 	}
 }
 
 func Log(err error, errOpts ...ErrOption) *Error {
 	e := new(Error)
-	e.Error.Message = errors.Cause(err).Error()
-	e.Error.Trace = err.Error()
-	e.Error.Type = TypeInternalError
-	e.Error.StatusCode = http.StatusInternalServerError
-	e.Error.Priority = PriorityMedium
-	e.Error.Severity = SeverityError
+	e.Error.Message = Cause(err).Error()
+	e.Error.Trace = traceOf(err)
 	e.NotifyTo = nil
 	e.OutputWriter = os.Stderr
 	e.HTTPWriter = nil
+	e.err = err
+	e.reporter = DefaultReporter()
+	e.encoding = DefaultEncoding()
+
+	// Inherit the spec attached at the origin of the error, if any.
+	if spec, ok := SpecOf(err); ok {
+		e.Error.inherit(spec)
+	}
 
 	e.setOptions(errOpts...)
-	e.logError()
+
+	// Fill the fields not set so far with the defaults of the error type.
+	e.Error.setDefaults()
+
+	e.Error.Message = Redact(e.Error.localizedMessage(DefaultLanguage))
+	e.Error.Trace = Redact(e.Error.Trace)
+
+	if !e.dedup() {
+		e.logError()
+		e.notify()
+	}
 
 	if e.HTTPWriter != nil {
-		e.HTTPWriter.WriteHeader(e.Error.StatusCode)
-		if err := json.NewEncoder(e.HTTPWriter).Encode(e); err != nil {
-			http.Error(e.HTTPWriter, err.Error(), http.StatusInternalServerError)
-		}
+		e.writeHTTPResponse()
 	}
 
+	e.terminate()
+
 	return e
 }
 
+// Err returns the logged error as a *TypedError carrying the final ErrorSpec,
+// so it can be returned up the call chain without losing its classification.
 func (e *Error) Err() error {
-	return New(e.Error.Message)
-}
+	err := e.err
+	if err == nil {
+		err = New(e.Error.Message)
+	}
 
-func (e *Error) logError() {
-	// logrus.SetFormatter(&logrus.JSONFormatter{})
-	logrus.SetFormatter(&logrus.TextFormatter{
-		DisableColors:          false,
-		DisableLevelTruncation: true,
-		FullTimestamp:          true,
-	})
-	logrus.SetReportCaller(false)
-	logrus.SetOutput(e.OutputWriter)
-
-	// Only log the warning severity or above.
-	//logrus.SetLevel(logrus.WarnLevel)
-
-	switch e.Error.Severity {
-	case SeverityTrace:
-		{
-			logrus.WithFields(logrus.Fields{
-				"priority": e.Error.Priority,
-				"type":     e.Error.Type,
-				"code":     e.Error.StatusCode,
-				"trace":    e.Error.Trace,
-			}).Trace(e.Error.Message)
-		}
-	case SeverityDebug:
-		{
-			logrus.WithFields(logrus.Fields{
-				"priority": e.Error.Priority,
-				"type":     e.Error.Type,
-				"code":     e.Error.StatusCode,
-				"trace":    e.Error.Trace,
-			}).Debug(e.Error.Message)
-		}
-	case SeverityInfo:
-		{
-			logrus.WithFields(logrus.Fields{
-				"priority": e.Error.Priority,
-				"type":     e.Error.Type,
-				"code":     e.Error.StatusCode,
-			}).Info(e.Error.Message)
-		}
-	case SeverityWarning:
-		{
-			logrus.WithFields(logrus.Fields{
-				"priority": e.Error.Priority,
-				"type":     e.Error.Type,
-				"code":     e.Error.StatusCode,
-			}).Warn(e.Error.Message)
-		}
-	case SeverityError:
-		{
-			logrus.WithFields(logrus.Fields{
-				"priority": e.Error.Priority,
-				"type":     e.Error.Type,
-				"code":     e.Error.StatusCode,
-				"trace":    e.Error.Trace,
-			}).Error(e.Error.Message)
-		}
-	case SeverityFatal:
-		{
-			logrus.WithFields(logrus.Fields{
-				"priority": e.Error.Priority,
-				"type":     e.Error.Type,
-				"code":     e.Error.StatusCode,
-				"trace":    e.Error.Trace,
-			}).Fatal(e.Error.Message)
-		}
-	case SeverityPanic:
-		{
-			logrus.WithFields(logrus.Fields{
-				"priority": e.Error.Priority,
-				"type":     e.Error.Type,
-				"code":     e.Error.StatusCode,
-				"trace":    e.Error.Trace,
-			}).Panic(e.Error.Message)
-		}
+	return &TypedError{
+		Spec: e.Error,
+		err:  err,
 	}
+}
 
-	//log.Printf("ERROR: %v\n", e.Error.Message)
+func (e *Error) logError() {
+	e.reporter.Report(e)
 }
 
+// New returns an error with the given message and the stack at the point it
+// was called.
 func New(msg string) error {
-	return errors.New(msg)
+	return &stackError{
+		msg:   msg,
+		stack: callers(1),
+	}
 }
 
+// Errorf formats according to a format specifier and returns the result as
+// an error with the stack at the point it was called.
 func Errorf(format string, args ...interface{}) error {
-	return errors.Errorf(format, args...)
+	return &stackError{
+		msg:   fmt.Sprintf(format, args...),
+		stack: callers(1),
+	}
 }
 
+// Wrapf annotates err with a formatted message. The stack at the point
+// Wrapf was called is recorded only if err doesn't carry one already.
+// Wrapf returns nil if err is nil.
 func Wrapf(err error, format string, args ...interface{}) error {
-	return errors.Wrapf(err, format, args...)
+	if err == nil {
+		return nil
+	}
+
+	e := &stackError{
+		msg:   fmt.Sprintf(format, args...),
+		cause: err,
+	}
+	if len(StackOf(err)) == 0 {
+		e.stack = callers(1)
+	}
+
+	return e
 }
 
+// Cause returns the underlying cause of err, following the chain of errors
+// implementing the github.com/pkg/errors causer interface.
 func Cause(err error) error {
-	return errors.Cause(err)
+	type causer interface {
+		Cause() error
+	}
+
+	for err != nil {
+		c, ok := err.(causer)
+		if !ok || c.Cause() == nil {
+			break
+		}
+		err = c.Cause()
+	}
+
+	return err
+}
+
+func Unwrap(err error) error {
+	return errors.Unwrap(err)
 }
 
+func Is(err, target error) bool {
+	return errors.Is(err, target)
+}
+
+func As(err error, target interface{}) bool {
+	return errors.As(err, target)
+}
+
+// Errs aggregates errs into a *MultiError, or returns nil if there is no
+// non-nil error in errs.
 func Errs(errs []error) error {
-	if len(errs) == 0 {
-		return nil
-	}
+	m := new(MultiError)
+	m.Append(errs...)
 
-	errMsg := "ERRORS: |+| "
-	for _, err := range errs {
-		errMsg = errMsg + fmt.Sprint(err) + " |+| "
-	}
-	return New(errMsg)
+	return m.ErrorOrNil()
 }
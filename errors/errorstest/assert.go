@@ -0,0 +1,161 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errorstest
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"x6a.dev/pkg/errors"
+)
+
+func specOf(t testing.TB, err error) (errors.ErrorSpec, bool) {
+	t.Helper()
+
+	if err == nil {
+		t.Errorf("got nil error, want a typed error")
+		return errors.ErrorSpec{}, false
+	}
+
+	spec, ok := errors.SpecOf(err)
+	if !ok {
+		t.Errorf("error %q is not a typed error", err)
+		return errors.ErrorSpec{}, false
+	}
+
+	return spec, true
+}
+
+// AssertType checks that err carries the given error type.
+func AssertType(t testing.TB, err error, typ string) {
+	t.Helper()
+
+	if spec, ok := specOf(t, err); ok && spec.Type != typ {
+		t.Errorf("error %q: got type %q, want %q", err, spec.Type, typ)
+	}
+}
+
+// AssertStatusCode checks that err carries the given HTTP status code.
+func AssertStatusCode(t testing.TB, err error, statusCode int) {
+	t.Helper()
+
+	if spec, ok := specOf(t, err); ok && spec.StatusCode != statusCode {
+		t.Errorf("error %q: got status code %d, want %d", err, spec.StatusCode, statusCode)
+	}
+}
+
+// AssertSeverity checks that err carries the given severity.
+func AssertSeverity(t testing.TB, err error, severity errors.Severity) {
+	t.Helper()
+
+	if spec, ok := specOf(t, err); ok && spec.Severity != severity {
+		t.Errorf("error %q: got severity %v, want %v", err, spec.Severity, severity)
+	}
+}
+
+// AssertSpec checks that got matches the non-zero fields of want.
+func AssertSpec(t testing.TB, got, want errors.ErrorSpec) {
+	t.Helper()
+
+	check := func(field, got, want string) {
+		t.Helper()
+		if len(want) > 0 && got != want {
+			t.Errorf("got %v %q, want %q", field, got, want)
+		}
+	}
+
+	check("code", got.Code, want.Code)
+	check("message", got.Message, want.Message)
+	check("type", got.Type, want.Type)
+	check("priority", string(got.Priority), string(want.Priority))
+	check("severity", string(got.Severity), string(want.Severity))
+	check("requestId", got.RequestID, want.RequestID)
+	check("userId", got.UserID, want.UserID)
+	check("tenant", got.Tenant, want.Tenant)
+	check("traceId", got.TraceID, want.TraceID)
+	check("spanId", got.SpanID, want.SpanID)
+
+	if want.StatusCode != 0 && got.StatusCode != want.StatusCode {
+		t.Errorf("got status code %d, want %d", got.StatusCode, want.StatusCode)
+	}
+}
+
+// AssertLogged checks that the last error reported to r matches the non-zero
+// fields of want.
+func AssertLogged(t testing.TB, r *Recorder, want errors.ErrorSpec) {
+	t.Helper()
+
+	got, ok := r.Last()
+	if !ok {
+		t.Errorf("no error was reported")
+		return
+	}
+
+	AssertSpec(t, got, want)
+}
+
+// Response decodes the error response written by Log to the SetHTTPResponse
+// writer, either the JSON Error document or the RFC 7807 problem+json one
+// (see SetProblemJSON).
+func Response(t testing.TB, w *httptest.ResponseRecorder) errors.ErrorSpec {
+	t.Helper()
+
+	contentType := w.Header().Get("Content-Type")
+
+	switch {
+	case strings.HasPrefix(contentType, errors.ContentTypeProblemJSON):
+		var p errors.Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatalf("invalid problem+json response %q: %v", w.Body.String(), err)
+		}
+		return errors.ErrorSpec{
+			Code:       p.Code,
+			Message:    p.Detail,
+			Trace:      p.Trace,
+			Type:       p.ErrorType,
+			StatusCode: p.Status,
+			Priority:   p.Priority,
+			Severity:   p.Severity,
+			RequestID:  p.RequestID,
+			TraceID:    p.TraceID,
+		}
+
+	case strings.HasPrefix(contentType, errors.ContentTypeJSON):
+		var e errors.Error
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+			t.Fatalf("invalid JSON response %q: %v", w.Body.String(), err)
+		}
+		return e.Error
+	}
+
+	t.Fatalf("unexpected error response content type %q", contentType)
+
+	return errors.ErrorSpec{}
+}
+
+// AssertResponse checks that w holds an error response with the given HTTP
+// status code, whose body matches the non-zero fields of want.
+func AssertResponse(t testing.TB, w *httptest.ResponseRecorder, statusCode int, want errors.ErrorSpec) {
+	t.Helper()
+
+	if w.Code != statusCode {
+		t.Errorf("got response status %d, want %d", w.Code, statusCode)
+	}
+
+	AssertSpec(t, Response(t, w), want)
+}
@@ -0,0 +1,99 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package errorstest provides helpers to test the code using the errors
+// package: a Recorder capturing what Log reports, assertions on the
+// classification of errors and HTTP error responses, and golden file
+// comparison of rendered errors.
+package errorstest
+
+import (
+	"sync"
+
+	"x6a.dev/pkg/errors"
+)
+
+// Recorder is an in-memory errors.Reporter keeping the specs of the reported
+// errors.
+type Recorder struct {
+	mu       sync.Mutex
+	specs    []errors.ErrorSpec
+	previous errors.Reporter
+}
+
+// Record installs a new Recorder as the default reporter. Close restores the
+// previous one:
+//
+//	rec := errorstest.Record()
+//	defer rec.Close()
+func Record() *Recorder {
+	r := &Recorder{
+		previous: errors.DefaultReporter(),
+	}
+	errors.SetDefaultReporter(r)
+
+	return r
+}
+
+// Report implements errors.Reporter.
+func (r *Recorder) Report(e *errors.Error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.specs = append(r.specs, e.Error)
+}
+
+// Errors returns the specs of the reported errors, oldest first.
+func (r *Recorder) Errors() []errors.ErrorSpec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]errors.ErrorSpec(nil), r.specs...)
+}
+
+// Len returns the number of reported errors.
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.specs)
+}
+
+// Last returns the spec of the last reported error, if any.
+func (r *Recorder) Last() (errors.ErrorSpec, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.specs) == 0 {
+		return errors.ErrorSpec{}, false
+	}
+
+	return r.specs[len(r.specs)-1], true
+}
+
+// Reset discards the reported errors.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.specs = nil
+}
+
+// Close restores the default reporter installed before Record.
+func (r *Recorder) Close() {
+	if r.previous != nil {
+		errors.SetDefaultReporter(r.previous)
+	}
+}
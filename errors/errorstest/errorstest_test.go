@@ -0,0 +1,139 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errorstest_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"x6a.dev/pkg/errors"
+	"x6a.dev/pkg/errors/errorstest"
+)
+
+// fakeT records the failures of the assertions under test.
+type fakeT struct {
+	testing.TB
+	failures []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) Fatalf(format string, args ...interface{}) {
+	t.Errorf(format, args...)
+}
+
+func TestRecorder(t *testing.T) {
+	rec := errorstest.Record()
+
+	errors.Log(errors.NotFound("user %v", "alice"), errors.SetOutput(ioutil.Discard))
+	errors.Log(errors.New("boom"), errors.SetOutput(ioutil.Discard))
+
+	if rec.Len() != 2 {
+		t.Fatalf("got %d reported errors, want 2", rec.Len())
+	}
+	if specs := rec.Errors(); specs[0].Type != errors.TypeNotFound {
+		t.Errorf("got first error type %q, want %q", specs[0].Type, errors.TypeNotFound)
+	}
+	errorstest.AssertLogged(t, rec, errors.ErrorSpec{Message: "boom", Type: errors.TypeInternalError})
+
+	rec.Reset()
+	if _, ok := rec.Last(); ok {
+		t.Error("got a reported error after Reset")
+	}
+
+	rec.Close()
+	if errors.DefaultReporter() == errors.Reporter(rec) {
+		t.Error("the recorder is still the default reporter after Close")
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	err := errors.NotFound("user %v", "alice")
+
+	ok := &fakeT{TB: t}
+	errorstest.AssertType(ok, err, errors.TypeNotFound)
+	errorstest.AssertStatusCode(ok, err, http.StatusNotFound)
+	errorstest.AssertSpec(ok, errors.ErrorSpec{Type: errors.TypeNotFound, Code: "X"}, errors.ErrorSpec{Type: errors.TypeNotFound})
+	if len(ok.failures) > 0 {
+		t.Errorf("got failures %q, want none", ok.failures)
+	}
+
+	for name, assert := range map[string]func(testing.TB){
+		"type":        func(t testing.TB) { errorstest.AssertType(t, err, errors.TypeConflict) },
+		"status code": func(t testing.TB) { errorstest.AssertStatusCode(t, err, http.StatusConflict) },
+		"severity":    func(t testing.TB) { errorstest.AssertSeverity(t, err, errors.SeverityFatal) },
+		"untyped":     func(t testing.TB) { errorstest.AssertType(t, errors.New("boom"), errors.TypeNotFound) },
+		"nil":         func(t testing.TB) { errorstest.AssertType(t, nil, errors.TypeNotFound) },
+		"spec":        func(t testing.TB) { errorstest.AssertSpec(t, errors.ErrorSpec{}, errors.ErrorSpec{Message: "boom"}) },
+	} {
+		fail := &fakeT{TB: t}
+		assert(fail)
+		if len(fail.failures) != 1 {
+			t.Errorf("%v: got failures %q, want 1", name, fail.failures)
+		}
+	}
+}
+
+func TestAssertResponse(t *testing.T) {
+	for _, opt := range []errors.ErrOption{
+		errors.SetType(errors.TypeNotFound),
+		errors.SetProblemJSON("/users/alice"),
+	} {
+		w := httptest.NewRecorder()
+		errors.Log(errors.NotFound("user %v", "alice"), opt, errors.SetHTTPResponse(w), errors.SetReporter(errors.NopReporter{}))
+
+		errorstest.AssertResponse(t, w, http.StatusNotFound, errors.ErrorSpec{
+			Type:       errors.TypeNotFound,
+			StatusCode: http.StatusNotFound,
+		})
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "errorstest")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "testdata", "not_found.json")
+	spec := errors.ErrorSpec{Message: "user alice not found", Type: errors.TypeNotFound, Trace: "stack"}
+
+	defer os.Unsetenv(errorstest.UpdateEnv)
+	os.Setenv(errorstest.UpdateEnv, "1")
+	errorstest.AssertGolden(t, spec, path)
+	os.Unsetenv(errorstest.UpdateEnv)
+
+	// The trace is left out of the rendering.
+	spec.Trace = "other stack"
+	errorstest.AssertGolden(t, spec, path)
+
+	fail := &fakeT{TB: t}
+	spec.Message = "other message"
+	errorstest.AssertGolden(fail, spec, path)
+	if len(fail.failures) != 1 {
+		t.Errorf("got failures %q, want the golden file mismatch", fail.failures)
+	}
+}
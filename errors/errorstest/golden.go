@@ -0,0 +1,76 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errorstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"x6a.dev/pkg/errors"
+)
+
+// UpdateEnv is the environment variable that, when set to a non-empty value,
+// makes AssertGolden write the golden files instead of comparing them (e.g.
+// ERRORSTEST_UPDATE=1 go test ./...).
+const UpdateEnv = "ERRORSTEST_UPDATE"
+
+// RenderJSON renders spec as indented JSON. The trace, which depends on the
+// build and the file layout, is left out so the output is stable.
+func RenderJSON(spec errors.ErrorSpec) ([]byte, error) {
+	spec.Trace = ""
+
+	b, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return nil, errors.Wrapf(err, "function json.MarshalIndent()")
+	}
+
+	return append(b, '\n'), nil
+}
+
+// AssertGolden compares the JSON rendering of spec (see RenderJSON) with the
+// content of the golden file at path, usually under testdata. The golden file
+// is written instead if the UpdateEnv environment variable is set.
+func AssertGolden(t testing.TB, spec errors.ErrorSpec, path string) {
+	t.Helper()
+
+	got, err := RenderJSON(spec)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if len(os.Getenv(UpdateEnv)) > 0 {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("function os.MkdirAll(): %v", err)
+		}
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("function ioutil.WriteFile(): %v", err)
+		}
+		return
+	}
+
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("function ioutil.ReadFile(): %v (run with %v=1 to create it)", err, UpdateEnv)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("error JSON doesn't match golden file %v\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
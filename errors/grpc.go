@@ -0,0 +1,298 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var grpcCodes = map[string]codes.Code{
+	TypeOK:                 codes.OK,
+	TypeInternalError:      codes.Internal,
+	TypeConfigurationError: codes.FailedPrecondition,
+	TypeSecurity:           codes.PermissionDenied,
+	TypeInvalidData:        codes.InvalidArgument,
+	TypeNetworkUnreachable: codes.Unavailable,
+	TypeNotFound:           codes.NotFound,
+	TypeConflict:           codes.AlreadyExists,
+	TypeUnauthorized:       codes.Unauthenticated,
+	TypeForbidden:          codes.PermissionDenied,
+	TypeRateLimited:        codes.ResourceExhausted,
+	TypeTimeout:            codes.DeadlineExceeded,
+	TypeUnavailable:        codes.Unavailable,
+	TypeCanceled:           codes.Canceled,
+	TypeUnimplemented:      codes.Unimplemented,
+}
+
+var grpcTypes = map[codes.Code]string{
+	codes.OK:                 TypeOK,
+	codes.Unknown:            TypeInternalError,
+	codes.Internal:           TypeInternalError,
+	codes.DataLoss:           TypeInternalError,
+	codes.FailedPrecondition: TypeConfigurationError,
+	codes.PermissionDenied:   TypeForbidden,
+	codes.Unauthenticated:    TypeUnauthorized,
+	codes.InvalidArgument:    TypeInvalidData,
+	codes.OutOfRange:         TypeInvalidData,
+	codes.Unavailable:        TypeUnavailable,
+	codes.NotFound:           TypeNotFound,
+	codes.AlreadyExists:      TypeConflict,
+	codes.Aborted:            TypeConflict,
+	codes.ResourceExhausted:  TypeRateLimited,
+	codes.DeadlineExceeded:   TypeTimeout,
+	codes.Canceled:           TypeCanceled,
+	codes.Unimplemented:      TypeUnimplemented,
+}
+
+var grpcHTTPStatusCodes = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           StatusClientClosedRequest,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+var httpGRPCCodes = map[int]codes.Code{
+	http.StatusOK:                  codes.OK,
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.AlreadyExists,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+	StatusClientClosedRequest:      codes.Canceled,
+	http.StatusInternalServerError: codes.Internal,
+	http.StatusNotImplemented:      codes.Unimplemented,
+	http.StatusServiceUnavailable:  codes.Unavailable,
+	http.StatusGatewayTimeout:      codes.DeadlineExceeded,
+}
+
+// GRPCCode returns the gRPC status code for the error type t.
+func GRPCCode(t string) codes.Code {
+	if c, ok := grpcCodes[t]; ok {
+		return c
+	}
+
+	return codes.Unknown
+}
+
+// TypeFromGRPCCode returns the error type for the gRPC status code c.
+func TypeFromGRPCCode(c codes.Code) string {
+	if t, ok := grpcTypes[c]; ok {
+		return t
+	}
+
+	return TypeInternalError
+}
+
+func (s ErrorSpec) grpcCode() codes.Code {
+	if c, ok := grpcCodes[s.Type]; ok {
+		return c
+	}
+
+	if c, ok := httpGRPCCodes[s.StatusCode]; ok {
+		return c
+	}
+
+	return codes.Unknown
+}
+
+// ToGRPCStatus converts err into a gRPC status. The status code is derived
+// from err's ErrorSpec, which is also carried in the status details so the
+// priority and severity survive the round trip. Errors that already are gRPC
+// statuses are returned unchanged.
+func ToGRPCStatus(err error) *status.Status {
+	if err == nil {
+		return nil
+	}
+
+	var se interface {
+		GRPCStatus() *status.Status
+	}
+	if As(err, &se) {
+		return se.GRPCStatus()
+	}
+
+	spec, ok := SpecOf(err)
+	if !ok {
+		spec = ErrorSpec{
+			Type:       TypeInternalError,
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	// The status message reaches the client: use the code message, if any,
+	// and redact it like the HTTP responses.
+	msg := spec.localizedMessage(DefaultLanguage)
+	if len(msg) == 0 {
+		msg = Cause(err).Error()
+	}
+
+	st := status.New(spec.grpcCode(), Redact(msg))
+
+	details := &structpb.Struct{
+		Fields: map[string]*structpb.Value{
+			"code":       grpcStringValue(spec.Code),
+			"type":       grpcStringValue(spec.Type),
+			"statusCode": {Kind: &structpb.Value_NumberValue{NumberValue: float64(spec.StatusCode)}},
+			"priority":   grpcStringValue(string(spec.Priority)),
+			"severity":   grpcStringValue(string(spec.Severity)),
+		},
+	}
+	if stWithDetails, err := st.WithDetails(details); err == nil {
+		return stWithDetails
+	}
+
+	return st
+}
+
+func grpcStringValue(s string) *structpb.Value {
+	return &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: s}}
+}
+
+// FromGRPCStatus converts a gRPC status into a *TypedError. The ErrorSpec is
+// taken from the status details if present, or derived from the status code
+// otherwise.
+func FromGRPCStatus(st *status.Status) error {
+	if st == nil || st.Code() == codes.OK {
+		return nil
+	}
+
+	spec := ErrorSpec{
+		Message:    st.Message(),
+		Type:       TypeFromGRPCCode(st.Code()),
+		StatusCode: grpcHTTPStatusCodes[st.Code()],
+	}
+
+	for _, d := range st.Details() {
+		details, ok := d.(*structpb.Struct)
+		if !ok {
+			continue
+		}
+
+		fields := details.GetFields()
+		spec.Code = fields["code"].GetStringValue()
+		if t := fields["type"].GetStringValue(); len(t) > 0 {
+			spec.Type = t
+		}
+		if code := int(fields["statusCode"].GetNumberValue()); code != 0 {
+			spec.StatusCode = code
+		}
+		spec.Priority = Priority(fields["priority"].GetStringValue())
+		spec.Severity = Severity(fields["severity"].GetStringValue())
+	}
+
+	return &TypedError{
+		Spec: spec,
+		err:  st.Err(),
+	}
+}
+
+// FromGRPCError converts err into a *TypedError if it is a gRPC status error,
+// or returns it unchanged otherwise.
+func FromGRPCError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	return FromGRPCStatus(st)
+}
+
+// UnaryServerInterceptor converts the errors returned by unary handlers into
+// gRPC statuses with ToGRPCStatus.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, ToGRPCStatus(err).Err()
+		}
+
+		return resp, nil
+	}
+}
+
+// StreamServerInterceptor converts the errors returned by stream handlers
+// into gRPC statuses with ToGRPCStatus.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return ToGRPCStatus(err).Err()
+		}
+
+		return nil
+	}
+}
+
+// UnaryClientInterceptor converts the gRPC status errors returned by unary
+// calls into *TypedError with FromGRPCError.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return FromGRPCError(invoker(ctx, method, req, reply, cc, opts...))
+	}
+}
+
+// StreamClientInterceptor converts the gRPC status errors returned by
+// streaming calls into *TypedError with FromGRPCError.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, FromGRPCError(err)
+		}
+
+		return &grpcClientStream{ClientStream: cs}, nil
+	}
+}
+
+type grpcClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *grpcClientStream) SendMsg(m interface{}) error {
+	return grpcStreamError(s.ClientStream.SendMsg(m))
+}
+
+func (s *grpcClientStream) RecvMsg(m interface{}) error {
+	return grpcStreamError(s.ClientStream.RecvMsg(m))
+}
+
+func grpcStreamError(err error) error {
+	if err == io.EOF {
+		return err
+	}
+
+	return FromGRPCError(err)
+}
@@ -0,0 +1,90 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors_test
+
+import (
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"x6a.dev/pkg/errors"
+	"x6a.dev/pkg/errors/errorstest"
+)
+
+func TestGRPCRoundTrip(t *testing.T) {
+	err := errors.Typed(errors.New("lookup failed: password=hunter2"),
+		errors.SetType(errors.TypeNotFound),
+		errors.SetPriority(errors.PriorityHigh),
+		errors.SetSeverity(errors.SeverityWarning),
+	)
+
+	st := errors.ToGRPCStatus(err)
+	if st.Code() != codes.NotFound {
+		t.Errorf("got code %v, want NotFound", st.Code())
+	}
+	if want := "lookup failed: password=[REDACTED]"; st.Message() != want {
+		t.Errorf("got status message %q, want %q", st.Message(), want)
+	}
+
+	got := errors.FromGRPCError(st.Err())
+	errorstest.AssertSpec(t, mustSpec(t, got), errors.ErrorSpec{
+		Message:    "lookup failed: password=[REDACTED]",
+		Type:       errors.TypeNotFound,
+		StatusCode: http.StatusNotFound,
+		Priority:   errors.PriorityHigh,
+		Severity:   errors.SeverityWarning,
+	})
+	if st, ok := status.FromError(errors.Unwrap(got)); !ok || st.Code() != codes.NotFound {
+		t.Error("the converted error doesn't wrap the gRPC status")
+	}
+}
+
+func TestGRPCCodeMessage(t *testing.T) {
+	registerTestCode(t)
+
+	err := errors.Typed(errors.New("sql: no rows"), errors.SetCode("TEST_USER_NOT_FOUND", map[string]interface{}{"user": "alice"}))
+
+	st := errors.ToGRPCStatus(err)
+	if st.Message() != "user alice not found" {
+		t.Errorf("got status message %q, want the code message", st.Message())
+	}
+	if code := errors.CodeOf(errors.FromGRPCStatus(st)); code != "TEST_USER_NOT_FOUND" {
+		t.Errorf("got code %q after the round trip", code)
+	}
+}
+
+func TestGRPCStatusWithoutDetails(t *testing.T) {
+	err := errors.FromGRPCError(status.Error(codes.Unavailable, "down"))
+
+	errorstest.AssertType(t, err, errors.TypeUnavailable)
+	errorstest.AssertStatusCode(t, err, http.StatusServiceUnavailable)
+	if errors.FromGRPCError(nil) != nil {
+		t.Error("FromGRPCError(nil) != nil")
+	}
+}
+
+func mustSpec(t *testing.T, err error) errors.ErrorSpec {
+	t.Helper()
+
+	spec, ok := errors.SpecOf(err)
+	if !ok {
+		t.Fatalf("%v isn't a typed error", err)
+	}
+
+	return spec
+}
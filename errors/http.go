@@ -0,0 +1,141 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	ContentTypeJSON        = "application/json"
+	ContentTypeProblemJSON = "application/problem+json"
+)
+
+// StatusClientClosedRequest is the non-standard status code of TypeCanceled
+// errors, the client having gone before the response was sent.
+const StatusClientClosedRequest = 499
+
+// ProblemBaseURI is prepended to the error type to build the problem type
+// URI (e.g. "https://example.com/problems/" renders the TypeSecurity problem
+// type as "https://example.com/problems/Security"). If empty, the problem type
+// is "about:blank".
+var ProblemBaseURI string
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	// Extension members.
+	Code      string   `json:"code,omitempty"`
+	ErrorType string   `json:"errorType,omitempty"`
+	Priority  Priority `json:"priority,omitempty"`
+	Severity  Severity `json:"severity,omitempty"`
+	RequestID string   `json:"requestId,omitempty"`
+	TraceID   string   `json:"traceId,omitempty"`
+	Trace     string   `json:"trace,omitempty"`
+}
+
+type problemOptions struct {
+	instance string
+}
+
+// SetProblemJSON makes the response written to the SetHTTPResponse writer an
+// RFC 7807 application/problem+json document. The instance URI reference
+// identifies the occurrence of the problem (usually the request URI) and is
+// omitted if empty.
+func SetProblemJSON(instance string) ErrOption {
+	return ErrOption{
+		Key: OptionProblemJSON,
+		Value: &problemOptions{
+			instance: instance,
+		},
+	}
+}
+
+// Problem renders the spec as an RFC 7807 problem details object. The trace
+// is only included in binaries built with the debug tag.
+func (s ErrorSpec) Problem(instance string) *Problem {
+	p := &Problem{
+		Type:      "about:blank",
+		Title:     statusText(s.StatusCode),
+		Status:    s.StatusCode,
+		Detail:    s.Message,
+		Instance:  instance,
+		Code:      s.Code,
+		ErrorType: s.Type,
+		Priority:  s.Priority,
+		Severity:  s.Severity,
+		RequestID: s.RequestID,
+		TraceID:   s.TraceID,
+	}
+
+	if len(ProblemBaseURI) > 0 && len(s.Type) > 0 {
+		p.Type = ProblemBaseURI + s.Type
+		p.Title = s.Type
+	}
+
+	if debugBuild {
+		p.Trace = s.Trace
+	}
+
+	return p
+}
+
+// statusText is http.StatusText, knowing the non-standard status codes used
+// too.
+func statusText(code int) string {
+	if code == StatusClientClosedRequest {
+		return "Client Closed Request"
+	}
+
+	return http.StatusText(code)
+}
+
+// httpResponse is the body of the default HTTP error response.
+type httpResponse struct {
+	Error ErrorSpec `json:"error"`
+}
+
+func (e *Error) writeHTTPResponse() {
+	// Render the message in the language requested by the client.
+	spec := e.Error
+	spec.Message = Redact(e.Error.LocalizedMessage(e.acceptLanguage))
+
+	// Neither the stack trace nor the notification targets are for the
+	// client to see.
+	if !debugBuild {
+		spec.Trace = ""
+	}
+
+	var body interface{} = &httpResponse{Error: spec}
+	contentType := ContentTypeJSON
+
+	if e.problem != nil {
+		body = spec.Problem(e.problem.instance)
+		contentType = ContentTypeProblemJSON
+	}
+
+	e.HTTPWriter.Header().Set("Content-Type", contentType)
+	e.HTTPWriter.WriteHeader(e.Error.StatusCode)
+	if err := json.NewEncoder(e.HTTPWriter).Encode(body); err != nil {
+		http.Error(e.HTTPWriter, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// MultiError aggregates several errors keeping each one of them, so their
+// types and stack traces survive the aggregation. The zero value is ready to
+// use and it is safe to Append from concurrent goroutines.
+type MultiError struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+// Append adds the non-nil errs to m. An appended *MultiError is flattened
+// into m, and m appended to itself is ignored.
+func (m *MultiError) Append(errs ...error) {
+	// Flatten before locking m, so appending concurrently two MultiErrors to
+	// each other can't deadlock.
+	flat := make([]error, 0, len(errs))
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if me, ok := err.(*MultiError); ok {
+			if me != nil && me != m {
+				flat = append(flat, me.Errors()...)
+			}
+			continue
+		}
+		flat = append(flat, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.errs = append(m.errs, flat...)
+}
+
+// Errors returns a copy of the aggregated errors.
+func (m *MultiError) Errors() []error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	errs := make([]error, len(m.errs))
+	copy(errs, m.errs)
+
+	return errs
+}
+
+func (m *MultiError) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.errs)
+}
+
+// ErrorOrNil returns m as an error, or nil if no error has been appended.
+func (m *MultiError) ErrorOrNil() error {
+	if m == nil || m.Len() == 0 {
+		return nil
+	}
+
+	return m
+}
+
+func (m *MultiError) Error() string {
+	errs := m.Errors()
+
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = "\t* " + err.Error()
+	}
+
+	return multiErrorHeader(len(errs)) + "\n" + strings.Join(msgs, "\n")
+}
+
+// Is reports whether any of the aggregated errors matches target.
+func (m *MultiError) Is(target error) bool {
+	for _, err := range m.Errors() {
+		if Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first aggregated error that matches target.
+func (m *MultiError) As(target interface{}) bool {
+	for _, err := range m.Errors() {
+		if As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MarshalJSON renders m as a JSON array with an ErrorSpec per aggregated
+// error.
+func (m *MultiError) MarshalJSON() ([]byte, error) {
+	errs := m.Errors()
+
+	specs := make([]ErrorSpec, len(errs))
+	for i, err := range errs {
+		if spec, ok := SpecOf(err); ok {
+			specs[i] = spec
+		}
+		specs[i].Message = err.Error()
+	}
+
+	return json.Marshal(specs)
+}
+
+func (m *MultiError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			errs := m.Errors()
+			io.WriteString(s, multiErrorHeader(len(errs)))
+			for _, err := range errs {
+				fmt.Fprintf(s, "\n\t* %+v", err)
+			}
+			return
+		}
+		fallthrough
+	case 's':
+		io.WriteString(s, m.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", m.Error())
+	}
+}
+
+func multiErrorHeader(n int) string {
+	if n == 1 {
+		return "1 error occurred:"
+	}
+
+	return fmt.Sprintf("%d errors occurred:", n)
+}
@@ -0,0 +1,99 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"x6a.dev/pkg/errors"
+)
+
+func TestMultiErrorConcurrentAppend(t *testing.T) {
+	var m errors.MultiError
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.Append(errors.Errorf("error %d/%d", i, j))
+				_ = m.Error()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if m.Len() != 1000 {
+		t.Errorf("got %d errors, want 1000", m.Len())
+	}
+}
+
+func TestMultiErrorMutualAppend(t *testing.T) {
+	var a, b errors.MultiError
+	a.Append(errors.New("a"))
+	b.Append(errors.New("b"))
+
+	// Appending two MultiErrors to each other concurrently mustn't deadlock.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				a.Append(&b)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				b.Append(&a)
+			}
+		}()
+		wg.Wait()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Append deadlocked")
+	}
+}
+
+func TestMultiErrorAppend(t *testing.T) {
+	var m errors.MultiError
+	if m.ErrorOrNil() != nil {
+		t.Error("ErrorOrNil() != nil without errors")
+	}
+
+	var inner errors.MultiError
+	inner.Append(errors.New("b"), nil, errors.NotFound("c"))
+
+	m.Append(errors.New("a"), &inner, nil)
+	m.Append(&m)
+
+	if m.Len() != 3 {
+		t.Errorf("got %d errors, want 3 flattened ones without the self append", m.Len())
+	}
+	if !errors.IsType(m.ErrorOrNil(), errors.TypeNotFound) {
+		t.Error("the aggregated NotFound error isn't found")
+	}
+}
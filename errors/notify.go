@@ -0,0 +1,241 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"x6a.dev/pkg/internal/notify"
+)
+
+// Notifier delivers an error to a NotifyTo target. The target is passed
+// verbatim, scheme included (e.g. "slack:#alerts", "mailto:ops@example.com"
+// or "https://example.com/hook").
+type Notifier interface {
+	Notify(to string, e *Error) error
+}
+
+type notifierCfg struct {
+	notifier    Notifier
+	minPriority Priority
+}
+
+var (
+	notifiersMu sync.RWMutex
+	notifiers   = map[string]*notifierCfg{}
+)
+
+const (
+	// notifyQueueSize is the number of notifications waiting to be delivered
+	// above which new ones are dropped.
+	notifyQueueSize = 256
+
+	// notifyTerminateTimeout bounds the time a fatal or panic error waits for
+	// the pending notifications to be delivered.
+	notifyTerminateTimeout = 10 * time.Second
+)
+
+// notifyJob is a notification to deliver, or a flush request if done is not
+// nil.
+type notifyJob struct {
+	cfg  *notifierCfg
+	to   string
+	e    *Error
+	done chan struct{}
+}
+
+var (
+	notifyOnce sync.Once
+	notifyJobs = make(chan *notifyJob, notifyQueueSize)
+)
+
+var priorityRanks = map[Priority]int{
+	PriorityLow:    0,
+	PriorityMedium: 1,
+	PriorityHigh:   2,
+	PriorityUrgent: 3,
+}
+
+// RegisterNotifier registers n as the Notifier for the NotifyTo targets with
+// the given scheme. Only errors with priority minPriority or above are
+// delivered through n.
+func RegisterNotifier(scheme string, minPriority Priority, n Notifier) {
+	notifiersMu.Lock()
+	defer notifiersMu.Unlock()
+
+	notifiers[strings.ToLower(scheme)] = &notifierCfg{
+		notifier:    n,
+		minPriority: minPriority,
+	}
+}
+
+func getNotifier(to string) (*notifierCfg, bool) {
+	i := strings.Index(to, ":")
+	if i < 0 {
+		return nil, false
+	}
+
+	notifiersMu.RLock()
+	defer notifiersMu.RUnlock()
+
+	n, ok := notifiers[strings.ToLower(to[:i])]
+	return n, ok
+}
+
+// notify queues the delivery of e to its NotifyTo targets, so Log never
+// waits for the notifiers.
+func (e *Error) notify() {
+	for _, to := range e.NotifyTo {
+		n, ok := getNotifier(to)
+		if !ok {
+			fmt.Fprintf(e.OutputWriter, "Unable to notify %v: no notifier registered\n", to)
+			continue
+		}
+
+		if priorityRanks[e.Error.Priority] < priorityRanks[n.minPriority] {
+			continue
+		}
+
+		startNotifier()
+
+		select {
+		case notifyJobs <- &notifyJob{cfg: n, to: to, e: e}:
+		default:
+			fmt.Fprintf(e.OutputWriter, "Unable to notify %v: too many pending notifications\n", to)
+		}
+	}
+}
+
+func startNotifier() {
+	notifyOnce.Do(func() {
+		go deliverNotifications()
+	})
+}
+
+// deliverNotifications delivers the queued notifications one at a time, in
+// order.
+func deliverNotifications() {
+	for job := range notifyJobs {
+		if job.done != nil {
+			close(job.done)
+			continue
+		}
+
+		if err := job.cfg.notifier.Notify(job.to, job.e); err != nil {
+			fmt.Fprintf(job.e.OutputWriter, "Unable to notify %v: %v\n", job.to, err)
+		}
+	}
+}
+
+// FlushNotifications waits until the notifications queued so far are
+// delivered or ctx is done. Call it before exiting so no notification is
+// lost.
+func FlushNotifications(ctx context.Context) error {
+	startNotifier()
+
+	done := make(chan struct{})
+	select {
+	case notifyJobs <- &notifyJob{done: done}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WebhookNotifier posts the JSON encoded error to the target URL. Register it
+// for the http and https schemes.
+type WebhookNotifier struct {
+	Client *http.Client
+}
+
+func (n *WebhookNotifier) Notify(to string, e *Error) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return Wrapf(err, "function json.Marshal(e)")
+	}
+
+	if err := notify.PostWebhook(n.Client, to, ContentTypeJSON, body); err != nil {
+		return Wrapf(err, "function notify.PostWebhook()")
+	}
+
+	return nil
+}
+
+// SMTPNotifier mails the error to the address of its targets, e.g.
+// "mailto:ops@example.com" (or "MAILTO:", or any other scheme it is
+// registered for).
+type SMTPNotifier struct {
+	Addr    string // host:port of the SMTP server
+	Auth    smtp.Auth
+	From    string
+	Timeout time.Duration // of the whole exchange, 30 seconds by default
+}
+
+func (n *SMTPNotifier) Notify(to string, e *Error) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Message: %s\n", e.Error.Message)
+	fmt.Fprintf(&body, "Type: %s\n", e.Error.Type)
+	fmt.Fprintf(&body, "Status code: %d\n", e.Error.StatusCode)
+	fmt.Fprintf(&body, "Priority: %s\n", e.Error.Priority)
+	fmt.Fprintf(&body, "Severity: %s\n", e.Error.Severity)
+	for _, k := range sortedKeys(e.Error.ContextFields()) {
+		fmt.Fprintf(&body, "%s: %s\n", k, e.Error.ContextFields()[k])
+	}
+	fmt.Fprintf(&body, "Trace: %s\n", e.Error.Trace)
+
+	// Strip the scheme, whatever its case, as getNotifier matches it.
+	addr := to
+	if i := strings.Index(to, ":"); i >= 0 {
+		addr = to[i+1:]
+	}
+
+	mail := &notify.Mail{
+		From:    n.From,
+		To:      []string{addr},
+		Subject: fmt.Sprintf("[%s] %s: %s", e.Error.Priority, e.Error.Type, e.Error.Message),
+		Body:    body.String(),
+	}
+
+	if err := mail.Send(n.Addr, n.Auth, n.Timeout); err != nil {
+		return Wrapf(err, "function notify.SendMail()")
+	}
+
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
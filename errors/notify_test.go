@@ -0,0 +1,104 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors_test
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"x6a.dev/pkg/errors"
+)
+
+// smtpRecipients is an SMTP stand-in returning the recipients of the
+// received mails.
+func smtpRecipients(t *testing.T) (string, <-chan string, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+
+	rcpts := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+
+				r := bufio.NewReader(conn)
+				fmt.Fprint(conn, "220 localhost\r\n")
+
+				data := false
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+
+					switch {
+					case data && line == ".\r\n":
+						data = false
+						fmt.Fprint(conn, "250 OK\r\n")
+					case data:
+					case strings.HasPrefix(line, "RCPT TO:"):
+						rcpts <- strings.TrimSpace(strings.TrimPrefix(line, "RCPT TO:"))
+						fmt.Fprint(conn, "250 OK\r\n")
+					case strings.HasPrefix(line, "DATA"):
+						data = true
+						fmt.Fprint(conn, "354 Go ahead\r\n")
+					case strings.HasPrefix(line, "QUIT"):
+						fmt.Fprint(conn, "221 Bye\r\n")
+						return
+					default:
+						fmt.Fprint(conn, "250 OK\r\n")
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	return ln.Addr().String(), rcpts, func() { ln.Close() }
+}
+
+func TestSMTPNotifierScheme(t *testing.T) {
+	addr, rcpts, stop := smtpRecipients(t)
+	defer stop()
+
+	n := &errors.SMTPNotifier{Addr: addr, From: "errors@example.com"}
+	e := &errors.Error{Error: errors.ErrorSpec{Message: "boom", Type: errors.TypeInternalError}}
+
+	for _, to := range []string{"mailto:ops@example.com", "MailTo:ops@example.com", "email:ops@example.com"} {
+		if err := n.Notify(to, e); err != nil {
+			t.Fatalf("Notify(%q) = %v", to, err)
+		}
+
+		select {
+		case rcpt := <-rcpts:
+			if rcpt != "<ops@example.com>" {
+				t.Errorf("Notify(%q): got recipient %q, want <ops@example.com>", to, rcpt)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Notify(%q): no mail received", to)
+		}
+	}
+}
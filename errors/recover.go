@@ -0,0 +1,156 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// panicError returns the recovered value v as an error carrying the stack of
+// the panicking goroutine. It must be called through logPanic from the
+// deferred function.
+func panicError(v interface{}) error {
+	e := &stackError{
+		msg:   "panic",
+		stack: callers(3),
+	}
+
+	if err, ok := v.(error); ok {
+		e.cause = err
+	} else {
+		e.msg = fmt.Sprintf("panic: %v", v)
+	}
+
+	return e
+}
+
+func logPanic(v interface{}, errOpts ...ErrOption) *Error {
+	opts := append([]ErrOption{
+		SetType(TypeInternalError),
+		SetStatusCode(http.StatusInternalServerError),
+		SetPriority(PriorityUrgent),
+		SetSeverity(SeverityError),
+	}, errOpts...)
+
+	return Log(panicError(v), opts...)
+}
+
+type recoverResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *recoverResponseWriter) WriteHeader(statusCode int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *recoverResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recoverResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack lets websocket and other upgrading handlers take over the
+// connection. No response is written to a hijacked connection on panic.
+func (w *recoverResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.wroteHeader = true
+	}
+
+	return conn, rw, err
+}
+
+func (w *recoverResponseWriter) Push(target string, opts *http.PushOptions) error {
+	p, ok := w.ResponseWriter.(http.Pusher)
+	if !ok {
+		return http.ErrNotSupported
+	}
+
+	return p.Push(target, opts)
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController.
+func (w *recoverResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// RecoverHandler returns a middleware recovering the panics in next. The
+// panic is logged with its stack as a TypeInternalError with PriorityUrgent
+// (errOpts are applied on top), and a problem+json response without any
+// internal detail is written to the client, unless the handler had already
+// written its response headers.
+func RecoverHandler(next http.Handler, errOpts ...ErrOption) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &recoverResponseWriter{ResponseWriter: w}
+
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			e := logPanic(v, append([]ErrOption{SetContext(r.Context())}, errOpts...)...)
+
+			if rw.wroteHeader {
+				return
+			}
+
+			spec := e.Error
+			spec.Message = http.StatusText(spec.StatusCode)
+
+			w.Header().Set("Content-Type", ContentTypeProblemJSON)
+			w.WriteHeader(spec.StatusCode)
+			json.NewEncoder(w).Encode(spec.Problem(r.URL.RequestURI()))
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// Go runs f in a new goroutine, recovering and logging its panics as
+// RecoverHandler does, so a failing background worker doesn't crash the
+// process.
+func Go(f func(), errOpts ...ErrOption) {
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				logPanic(v, errOpts...)
+			}
+		}()
+
+		f()
+	}()
+}
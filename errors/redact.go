@@ -0,0 +1,173 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// RedactedText replaces the redacted values.
+var RedactedText = "[REDACTED]"
+
+var defaultRedactedFields = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"api_key",
+	"api-key",
+	"apikey",
+	"authorization",
+	"credential",
+}
+
+var (
+	redactMu sync.RWMutex
+
+	redactedFields  = defaultRedactedFields
+	redactFieldsRe  = fieldsRegexp(defaultRedactedFields)
+	redactPatterns  []*regexp.Regexp
+	defaultPatterns = []*regexp.Regexp{
+		// JSON Web Tokens.
+		regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+		// AWS access key IDs.
+		regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	}
+	urlCredentialsRe = regexp.MustCompile(`://[^/\s:@]+:[^/\s@]+@`)
+
+	// authHeaderRe matches the credentials of an Authorization header, with
+	// their scheme, and authCredentialsRe the credentials following a bearer
+	// or basic scheme anywhere else (see isCredential).
+	authHeaderRe      = regexp.MustCompile(`(?i)(\bauthorization["']?\s*[:=]\s*["']?)(?:(?:bearer|basic|digest|negotiate|token)\s+)?[^\s"',;&}]+`)
+	authCredentialsRe = regexp.MustCompile(`(?i)\b(bearer|basic)(\s+)([A-Za-z0-9._~+/-]{8,}=*)`)
+)
+
+// isCredential tells whether v looks like a token or base64 credentials
+// rather than a word (e.g. "basic validation"): it has a digit, a symbol or
+// an uppercase letter past its first character.
+func isCredential(v string) bool {
+	for i, r := range v {
+		switch {
+		case r >= '0' && r <= '9', strings.ContainsRune("._~+/-=", r):
+			return true
+		case i > 0 && r >= 'A' && r <= 'Z':
+			return true
+		}
+	}
+
+	return false
+}
+
+func redactAuthCredentials(s string) string {
+	s = authHeaderRe.ReplaceAllString(s, "${1}"+RedactedText)
+
+	return authCredentialsRe.ReplaceAllStringFunc(s, func(m string) string {
+		sm := authCredentialsRe.FindStringSubmatch(m)
+		if !isCredential(sm[3]) {
+			return m
+		}
+
+		return sm[1] + sm[2] + RedactedText
+	})
+}
+
+// fieldsRegexp matches the values of the key/value pairs whose key contains
+// any of the given names, in the key=value, key: value and "key":"value"
+// forms.
+func fieldsRegexp(names []string) *regexp.Regexp {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = regexp.QuoteMeta(name)
+	}
+
+	return regexp.MustCompile(`(?i)([\w-]*(?:` + strings.Join(quoted, "|") + `)[\w-]*["']?\s*[:=]\s*["']?)([^\s"',;&}]+)`)
+}
+
+// AddRedactedFields adds names to the field names whose values are redacted.
+// Any key containing one of the names (case insensitive) is redacted.
+func AddRedactedFields(names ...string) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+
+	redactedFields = append(append([]string{}, redactedFields...), names...)
+	redactFieldsRe = fieldsRegexp(redactedFields)
+}
+
+// IsRedactedField reports whether the values of the key are redacted, that
+// is, whether it contains any of the redacted field names (see
+// AddRedactedFields).
+func IsRedactedField(key string) bool {
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+
+	key = strings.ToLower(key)
+	for _, name := range redactedFields {
+		if strings.Contains(key, strings.ToLower(name)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddRedactPatterns adds patterns whose matches are redacted.
+func AddRedactPatterns(patterns ...*regexp.Regexp) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+
+	redactPatterns = append(redactPatterns, patterns...)
+}
+
+// Redact scrubs the values of the sensitive fields and the secrets matching
+// the default (JWTs, AWS access keys, authorization credentials, URL
+// passwords) and configured patterns from s.
+func Redact(s string) string {
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+
+	s = redactAuthCredentials(s)
+	for _, re := range defaultPatterns {
+		s = re.ReplaceAllString(s, RedactedText)
+	}
+	for _, re := range redactPatterns {
+		s = re.ReplaceAllString(s, RedactedText)
+	}
+	s = urlCredentialsRe.ReplaceAllString(s, "://"+RedactedText+"@")
+
+	return redactFieldsRe.ReplaceAllString(s, "${1}"+RedactedText)
+}
+
+type sensitive struct {
+	value interface{}
+}
+
+// Sensitive marks v as sensitive, so it is formatted as RedactedText (e.g.
+// errors.Wrapf(err, "SET key (%v), value (%v)", k, errors.Sensitive(v))).
+func Sensitive(v interface{}) interface{} {
+	return sensitive{value: v}
+}
+
+func (s sensitive) String() string {
+	return RedactedText
+}
+
+func (s sensitive) Format(f fmt.State, verb rune) {
+	io.WriteString(f, RedactedText)
+}
@@ -0,0 +1,165 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Reporter is the sink Log writes errors to.
+type Reporter interface {
+	Report(e *Error)
+}
+
+var (
+	reporterMu      sync.RWMutex
+	defaultReporter Reporter = new(LogrusReporter)
+)
+
+// SetDefaultReporter sets the Reporter used by Log when no SetReporter
+// option is given. A nil r disables reporting.
+func SetDefaultReporter(r Reporter) {
+	if r == nil {
+		r = NopReporter{}
+	}
+
+	reporterMu.Lock()
+	defer reporterMu.Unlock()
+
+	defaultReporter = r
+}
+
+// DefaultReporter returns the Reporter used by Log when no SetReporter option
+// is given.
+func DefaultReporter() Reporter {
+	reporterMu.RLock()
+	defer reporterMu.RUnlock()
+
+	return defaultReporter
+}
+
+// NopReporter discards every error.
+type NopReporter struct{}
+
+func (NopReporter) Report(e *Error) {}
+
+// LogrusReporter writes errors through a logrus logger. If Logger is nil, a
+// private logger writing to the Error's OutputWriter in the requested
+// Encoding is used, so the global logrus configuration is never modified.
+type LogrusReporter struct {
+	Logger *logrus.Logger
+}
+
+func newLogrusLogger(out io.Writer, enc Encoding) *logrus.Logger {
+	logger := logrus.New()
+	// The text formatter only colors the output if it is a terminal.
+	logger.SetFormatter(&logrus.TextFormatter{
+		DisableColors:          enc == EncodingLogfmt,
+		DisableLevelTruncation: true,
+		FullTimestamp:          true,
+	})
+	logger.SetReportCaller(false)
+	logger.SetOutput(out)
+
+	return logger
+}
+
+// jsonRecord is the EncodingJSON output: the ErrorSpec fields along with the
+// time and level.
+type jsonRecord struct {
+	Time  string `json:"time"`
+	Level string `json:"level"`
+	ErrorSpec
+}
+
+func reportJSON(e *Error) {
+	rec := jsonRecord{
+		Time:      time.Now().Format(time.RFC3339Nano),
+		Level:     strings.ToLower(string(e.Error.Severity)),
+		ErrorSpec: e.Error,
+	}
+
+	if err := json.NewEncoder(e.OutputWriter).Encode(rec); err != nil {
+		fmt.Fprintf(e.OutputWriter, "Unable to encode error: %v\n", err)
+	}
+}
+
+func (r *LogrusReporter) Report(e *Error) {
+	logger := r.Logger
+	if logger == nil {
+		if e.encoding == EncodingJSON {
+			reportJSON(e)
+			return
+		}
+		logger = newLogrusLogger(e.OutputWriter, e.encoding)
+	}
+
+	fields := logrus.Fields{
+		"priority": e.Error.Priority,
+		"type":     e.Error.Type,
+		"code":     e.Error.StatusCode,
+	}
+	if len(e.Error.Code) > 0 {
+		fields["errorCode"] = e.Error.Code
+	}
+	for k, v := range e.Error.ContextFields() {
+		fields[k] = v
+	}
+
+	if e.Error.Severity != SeverityInfo && e.Error.Severity != SeverityWarning {
+		fields["trace"] = e.Error.Trace
+	}
+	if e.Error.Severity == SeverityCritical {
+		fields["severity"] = e.Error.Severity
+	}
+	entry := logger.WithFields(fields)
+
+	// Fatal and panic errors are logged without terminating: Log calls the
+	// fatal and panic hooks once every sink has been written.
+	switch e.Error.Severity {
+	case SeverityTrace:
+		entry.Trace(e.Error.Message)
+	case SeverityDebug:
+		entry.Debug(e.Error.Message)
+	case SeverityInfo:
+		entry.Info(e.Error.Message)
+	case SeverityWarning:
+		entry.Warn(e.Error.Message)
+	case SeverityFatal:
+		entry.Log(logrus.FatalLevel, e.Error.Message)
+	case SeverityPanic:
+		logrusPanic(entry, e.Error.Message)
+	default: // SeverityError and SeverityCritical, logrus has no critical level
+		entry.Error(e.Error.Message)
+	}
+}
+
+// logrusPanic writes a panic level entry. logrus panics right after writing
+// it, that panic is recovered so the package panic hook decides.
+func logrusPanic(entry *logrus.Entry, msg string) {
+	defer func() {
+		recover()
+	}()
+
+	entry.Log(logrus.PanicLevel, msg)
+}
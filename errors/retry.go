@@ -0,0 +1,281 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Class tells whether an error is worth retrying.
+type Class int
+
+const (
+	ClassPermanent Class = iota
+	ClassTemporary
+	ClassThrottled
+)
+
+func (c Class) String() string {
+	switch c {
+	case ClassTemporary:
+		return "temporary"
+	case ClassThrottled:
+		return "throttled"
+	}
+
+	return "permanent"
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+func (e *permanentError) Cause() error  { return e.err }
+
+// Permanent marks err as not worth retrying, whatever its classification.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+// Classify tells whether err is temporary, throttled or permanent.
+// Recognized temporary errors are context deadlines, net.Error timeouts,
+// errors implementing Temporary() bool, failures to dial a server (which may
+// not be up yet), the gRPC Unavailable,
+// DeadlineExceeded and Aborted codes, the HTTP 502, 503 and 504 status codes
+// and the TypeNetworkUnreachable, TypeUnavailable and TypeTimeout types.
+// Throttled errors are the gRPC ResourceExhausted code, the HTTP 429 status
+// code and the TypeRateLimited type. Anything else,
+// including context cancellation, is permanent.
+func Classify(err error) Class {
+	if err == nil {
+		return ClassPermanent
+	}
+
+	var pe *permanentError
+	if As(err, &pe) {
+		return ClassPermanent
+	}
+
+	if Is(err, context.Canceled) {
+		return ClassPermanent
+	}
+	if Is(err, context.DeadlineExceeded) {
+		return ClassTemporary
+	}
+
+	var se interface {
+		GRPCStatus() *status.Status
+	}
+	if As(err, &se) {
+		switch se.GRPCStatus().Code() {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted:
+			return ClassTemporary
+		case codes.ResourceExhausted:
+			return ClassThrottled
+		}
+		return ClassPermanent
+	}
+
+	if spec, ok := SpecOf(err); ok {
+		switch spec.StatusCode {
+		case http.StatusTooManyRequests:
+			return ClassThrottled
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return ClassTemporary
+		}
+		switch spec.Type {
+		case TypeRateLimited:
+			return ClassThrottled
+		case TypeNetworkUnreachable, TypeUnavailable, TypeTimeout:
+			return ClassTemporary
+		}
+	}
+
+	var ne net.Error
+	if As(err, &ne) && ne.Timeout() {
+		return ClassTemporary
+	}
+
+	var oe *net.OpError
+	if As(err, &oe) && oe.Op == "dial" {
+		return ClassTemporary
+	}
+
+	var te interface {
+		Temporary() bool
+	}
+	if As(err, &te) && te.Temporary() {
+		return ClassTemporary
+	}
+
+	return ClassPermanent
+}
+
+// IsTemporary reports whether err is worth retrying (temporary or
+// throttled).
+func IsTemporary(err error) bool {
+	return Classify(err) != ClassPermanent
+}
+
+// IsThrottled reports whether err is a rate limiting error.
+func IsThrottled(err error) bool {
+	return Classify(err) == ClassThrottled
+}
+
+// RetryOptions configures the exponential backoff of Retry.
+type RetryOptions struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	Jitter          float64 // randomization factor in [0, 1]
+
+	// MaxElapsedTime and MaxAttempts stop retrying once reached. Zero means
+	// no limit.
+	MaxElapsedTime time.Duration
+	MaxAttempts    int
+
+	// OnRetry, if not nil, is called before waiting for the next attempt.
+	OnRetry func(err error, wait time.Duration)
+}
+
+// DefaultRetryOptions are the options used by Retry when none are given.
+var DefaultRetryOptions = RetryOptions{
+	InitialInterval: 500 * time.Millisecond,
+	MaxInterval:     30 * time.Second,
+	Multiplier:      2,
+	Jitter:          0.5,
+	MaxElapsedTime:  5 * time.Minute,
+}
+
+// ConnectRetryOptions are the options used to connect to the databases,
+// whose servers may take a while to be up. Set OnRetry in a copy to warn
+// about the retries.
+var ConnectRetryOptions = RetryOptions{
+	InitialInterval: time.Second,
+	MaxInterval:     10 * time.Second,
+	Multiplier:      2,
+	Jitter:          0.5,
+	MaxElapsedTime:  time.Minute,
+}
+
+// Retry calls f until it succeeds, it returns a permanent error (see
+// Classify), the retry limits are reached or ctx is done. Waits grow
+// exponentially with random jitter; throttled errors wait for the
+// RetryAfter() time.Duration they carry, if any, or for MaxInterval. The
+// zero InitialInterval, MaxInterval and Multiplier of opts are taken from
+// DefaultRetryOptions.
+func Retry(ctx context.Context, opts *RetryOptions, f func() error) error {
+	if opts == nil {
+		opts = &DefaultRetryOptions
+	}
+	opts = opts.withDefaults()
+
+	start := time.Now()
+	interval := opts.InitialInterval
+
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil {
+			return nil
+		}
+
+		class := Classify(err)
+		if class == ClassPermanent {
+			return err
+		}
+		if opts.MaxAttempts > 0 && attempt >= opts.MaxAttempts {
+			return err
+		}
+
+		wait := jitter(interval, opts.Jitter)
+		if class == ClassThrottled {
+			wait = throttledWait(err, opts.MaxInterval)
+		}
+		if opts.MaxElapsedTime > 0 && time.Since(start)+wait > opts.MaxElapsedTime {
+			return err
+		}
+
+		if opts.OnRetry != nil {
+			opts.OnRetry(err, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return Errs([]error{ctx.Err(), err})
+		case <-timer.C:
+		}
+
+		interval = time.Duration(float64(interval) * opts.Multiplier)
+		if opts.MaxInterval > 0 && interval > opts.MaxInterval {
+			interval = opts.MaxInterval
+		}
+	}
+}
+
+// withDefaults returns a copy of o with the backoff intervals and multiplier
+// not set taken from DefaultRetryOptions, so Retry never busy-loops.
+func (o *RetryOptions) withDefaults() *RetryOptions {
+	opts := *o
+	if opts.InitialInterval <= 0 {
+		opts.InitialInterval = DefaultRetryOptions.InitialInterval
+	}
+	if opts.MaxInterval <= 0 {
+		opts.MaxInterval = DefaultRetryOptions.MaxInterval
+	}
+	if opts.Multiplier <= 0 {
+		opts.Multiplier = DefaultRetryOptions.Multiplier
+	}
+
+	return &opts
+}
+
+func jitter(interval time.Duration, factor float64) time.Duration {
+	if factor <= 0 {
+		return interval
+	}
+
+	delta := factor * float64(interval)
+	min := float64(interval) - delta
+	max := float64(interval) + delta
+
+	return time.Duration(min + rand.Float64()*(max-min))
+}
+
+func throttledWait(err error, maxInterval time.Duration) time.Duration {
+	var ra interface {
+		RetryAfter() time.Duration
+	}
+	if As(err, &ra) && ra.RetryAfter() > 0 {
+		return ra.RetryAfter()
+	}
+
+	return maxInterval
+}
@@ -0,0 +1,174 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"x6a.dev/pkg/errors"
+)
+
+type throttledError struct {
+	retryAfter time.Duration
+}
+
+func (e *throttledError) Error() string             { return "throttled" }
+func (e *throttledError) RetryAfter() time.Duration { return e.retryAfter }
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want errors.Class
+	}{
+		{nil, errors.ClassPermanent},
+		{errors.New("boom"), errors.ClassPermanent},
+		{context.Canceled, errors.ClassPermanent},
+		{errors.Wrapf(context.DeadlineExceeded, "function f()"), errors.ClassTemporary},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, errors.ClassTemporary},
+		{&net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}, errors.ClassPermanent},
+		{status.Error(codes.Unavailable, "unavailable"), errors.ClassTemporary},
+		{status.Error(codes.ResourceExhausted, "quota"), errors.ClassThrottled},
+		{status.Error(codes.InvalidArgument, "invalid"), errors.ClassPermanent},
+		{errors.Typed(errors.New("bad gateway"), errors.SetStatusCode(http.StatusBadGateway)), errors.ClassTemporary},
+		{errors.Typed(errors.New("slow down"), errors.SetStatusCode(http.StatusTooManyRequests)), errors.ClassThrottled},
+		{errors.Typed(errors.New("down"), errors.SetType(errors.TypeUnavailable)), errors.ClassTemporary},
+		{errors.NotFound("user %v", "alice"), errors.ClassPermanent},
+		{errors.Permanent(status.Error(codes.Unavailable, "unavailable")), errors.ClassPermanent},
+	} {
+		if got := errors.Classify(tc.err); got != tc.want {
+			t.Errorf("Classify(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	var waits []time.Duration
+	opts := &errors.RetryOptions{
+		InitialInterval: time.Millisecond,
+		MaxInterval:     4 * time.Millisecond,
+		Multiplier:      2,
+		MaxAttempts:     5,
+		OnRetry: func(err error, wait time.Duration) {
+			waits = append(waits, wait)
+		},
+	}
+
+	attempts := 0
+	err := errors.Retry(context.Background(), opts, func() error {
+		attempts++
+		return status.Error(codes.Unavailable, "unavailable")
+	})
+	if err == nil {
+		t.Fatal("Retry() = nil, want the last error")
+	}
+	if attempts != 5 {
+		t.Errorf("got %d attempts, want 5", attempts)
+	}
+	if got := fmt.Sprint(waits); got != "[1ms 2ms 4ms 4ms]" {
+		t.Errorf("got waits %v, want [1ms 2ms 4ms 4ms]", got)
+	}
+}
+
+func TestRetryDefaults(t *testing.T) {
+	var waits []time.Duration
+	opts := &errors.RetryOptions{
+		InitialInterval: time.Millisecond,
+		MaxAttempts:     3,
+		OnRetry: func(err error, wait time.Duration) {
+			waits = append(waits, wait)
+		},
+	}
+
+	errors.Retry(context.Background(), opts, func() error {
+		return status.Error(codes.Unavailable, "unavailable")
+	})
+
+	// The zero Multiplier is taken from DefaultRetryOptions.
+	if got := fmt.Sprint(waits); got != "[1ms 2ms]" {
+		t.Errorf("got waits %v, want [1ms 2ms]", got)
+	}
+}
+
+func TestRetryStops(t *testing.T) {
+	opts := &errors.RetryOptions{InitialInterval: time.Millisecond, MaxAttempts: 5}
+
+	attempts := 0
+	errors.Retry(context.Background(), opts, func() error {
+		attempts++
+		if attempts == 2 {
+			return errors.NotFound("user %v", "alice")
+		}
+		return status.Error(codes.Unavailable, "unavailable")
+	})
+	if attempts != 2 {
+		t.Errorf("got %d attempts, want to stop at the permanent error", attempts)
+	}
+
+	attempts = 0
+	err := errors.Retry(context.Background(), opts, func() error {
+		attempts++
+		if attempts == 3 {
+			return nil
+		}
+		return status.Error(codes.Unavailable, "unavailable")
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("Retry() = %v after %d attempts, want nil after 3", err, attempts)
+	}
+}
+
+func TestRetryThrottled(t *testing.T) {
+	var waits []time.Duration
+	opts := &errors.RetryOptions{
+		InitialInterval: time.Millisecond,
+		MaxAttempts:     2,
+		OnRetry: func(err error, wait time.Duration) {
+			waits = append(waits, wait)
+		},
+	}
+
+	errors.Retry(context.Background(), opts, func() error {
+		return errors.Typed(&throttledError{retryAfter: 3 * time.Millisecond}, errors.SetType(errors.TypeRateLimited))
+	})
+	if got := fmt.Sprint(waits); got != "[3ms]" {
+		t.Errorf("got waits %v, want the RetryAfter [3ms]", got)
+	}
+}
+
+func TestRetryContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := &errors.RetryOptions{
+		InitialInterval: time.Hour,
+		OnRetry: func(err error, wait time.Duration) {
+			cancel()
+		},
+	}
+
+	err := errors.Retry(ctx, opts, func() error {
+		return status.Error(codes.Unavailable, "unavailable")
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Retry() = %v, want context.Canceled", err)
+	}
+}
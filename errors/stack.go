@@ -0,0 +1,186 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// StackDepth is the maximum number of frames captured when an error is
+// created.
+var StackDepth = 32
+
+// StackTrimPrefixes are the path prefixes trimmed from the file names when a
+// stack is formatted (e.g. the module root or GOPATH).
+var StackTrimPrefixes []string
+
+// Frame is a single stack frame.
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// Stack is a call stack, innermost frame first.
+type Stack []Frame
+
+// Callers returns the stack of the calling goroutine, skipping skip frames
+// above the caller of Callers.
+func Callers(skip int) Stack {
+	return callers(skip + 1).frames()
+}
+
+type pcs []uintptr
+
+func callers(skip int) pcs {
+	pc := make([]uintptr, StackDepth)
+	n := runtime.Callers(skip+2, pc)
+
+	return pc[:n]
+}
+
+func (p pcs) frames() Stack {
+	if len(p) == 0 {
+		return nil
+	}
+
+	s := make(Stack, 0, len(p))
+	frames := runtime.CallersFrames(p)
+	for {
+		frame, more := frames.Next()
+		s = append(s, Frame{
+			Function: frame.Function,
+			File:     frame.File,
+			Line:     frame.Line,
+		})
+		if !more {
+			break
+		}
+	}
+
+	return s
+}
+
+func trimPath(file string) string {
+	for _, prefix := range StackTrimPrefixes {
+		if strings.HasPrefix(file, prefix) {
+			return strings.TrimPrefix(file[len(prefix):], "/")
+		}
+	}
+
+	return file
+}
+
+func (f Frame) String() string {
+	return fmt.Sprintf("%s\n\t%s:%d", f.Function, trimPath(f.File), f.Line)
+}
+
+func (s Stack) String() string {
+	lines := make([]string, len(s))
+	for i, f := range s {
+		lines[i] = f.String()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// StackOf returns the stack captured at the origin of err, that is, the
+// stack of the innermost error in the chain carrying one. Stacks captured by
+// github.com/pkg/errors are recognized too.
+func StackOf(err error) Stack {
+	var s Stack
+
+	for err != nil {
+		switch e := err.(type) {
+		case *stackError:
+			if len(e.stack) > 0 {
+				s = e.stack.frames()
+			}
+		case interface{ StackTrace() errors.StackTrace }:
+			st := e.StackTrace()
+			p := make(pcs, len(st))
+			for i, f := range st {
+				p[i] = uintptr(f)
+			}
+			s = p.frames()
+		}
+		err = Unwrap(err)
+	}
+
+	return s
+}
+
+// stackError is the error returned by New, Errorf and Wrapf. It records the
+// stack at the point it was created, unless the error it wraps already
+// carries one.
+type stackError struct {
+	msg   string
+	cause error
+	stack pcs
+}
+
+func (e *stackError) Error() string {
+	if e.cause == nil {
+		return e.msg
+	}
+
+	return e.msg + ": " + e.cause.Error()
+}
+
+func (e *stackError) Unwrap() error {
+	return e.cause
+}
+
+// Cause implements the github.com/pkg/errors causer interface.
+func (e *stackError) Cause() error {
+	return e.cause
+}
+
+// Format formats the error message. The %+v verb also prints the stack
+// captured at the origin of the error.
+func (e *stackError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			io.WriteString(s, e.Error())
+			if stack := StackOf(e); len(stack) > 0 {
+				io.WriteString(s, "\n"+stack.String())
+			}
+			return
+		}
+		fallthrough
+	case 's':
+		io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	}
+}
+
+// traceOf returns the message of err followed by the stack captured at its
+// origin.
+func traceOf(err error) string {
+	stack := StackOf(err)
+	if len(stack) == 0 {
+		return err.Error()
+	}
+
+	return err.Error() + "\n" + stack.String()
+}
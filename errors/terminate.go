@@ -0,0 +1,86 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors
+
+import (
+	"context"
+	"os"
+	"sync"
+)
+
+var (
+	hooksMu   sync.RWMutex
+	fatalHook = defaultFatalHook
+	panicHook = defaultPanicHook
+)
+
+func defaultFatalHook(e *Error) {
+	os.Exit(1)
+}
+
+func defaultPanicHook(e *Error) {
+	panic(e.Err())
+}
+
+// SetFatalHook sets the function Log calls after reporting a SeverityFatal
+// error. The default hook exits the process with status 1; libraries and
+// tests can install a hook running their shutdown logic instead, Log returns
+// normally if the hook does. A nil hook restores the default.
+func SetFatalHook(hook func(e *Error)) {
+	if hook == nil {
+		hook = defaultFatalHook
+	}
+
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	fatalHook = hook
+}
+
+// SetPanicHook sets the function Log calls after reporting a SeverityPanic
+// error. The default hook panics with the logged error. A nil hook restores
+// the default.
+func SetPanicHook(hook func(e *Error)) {
+	if hook == nil {
+		hook = defaultPanicHook
+	}
+
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	panicHook = hook
+}
+
+func (e *Error) terminate() {
+	hooksMu.RLock()
+	fatal, panicking := fatalHook, panicHook
+	hooksMu.RUnlock()
+
+	switch e.Error.Severity {
+	case SeverityFatal, SeverityPanic:
+		// Deliver the notifications before the process goes down.
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTerminateTimeout)
+		FlushNotifications(ctx)
+		cancel()
+	}
+
+	switch e.Error.Severity {
+	case SeverityFatal:
+		fatal(e)
+	case SeverityPanic:
+		panicking(e)
+	}
+}
@@ -21,6 +21,10 @@ import (
 	"runtime"
 )
 
+// Trace returns the immediate caller as "file:line | function".
+//
+// Deprecated: errors created with New, Errorf and Wrapf carry their full
+// stack, see StackOf.
 func Trace() string {
 	pc := make([]uintptr, 10) // at least 1 entry needed
 	runtime.Callers(2, pc)
@@ -29,6 +33,10 @@ func Trace() string {
 	return fmt.Sprintf("%s:%d | %s", filepath.Base(file), line, f.Name())
 }
 
+// Trace2 returns the immediate caller as "file:line/function".
+//
+// Deprecated: errors created with New, Errorf and Wrapf carry their full
+// stack, see StackOf.
 func Trace2() string {
 	pc := make([]uintptr, 15)
 	n := runtime.Callers(2, pc)
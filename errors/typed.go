@@ -0,0 +1,169 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors
+
+import (
+	"fmt"
+	"io"
+)
+
+// TypedError is an error carrying the ErrorSpec fields (type, status code,
+// priority and severity), so the classification attached at the origin of an
+// error can be recovered further up the call chain with As or SpecOf.
+type TypedError struct {
+	Spec ErrorSpec
+
+	err error
+}
+
+// Typed annotates err with the given spec options (SetType, SetStatusCode,
+// SetPriority and SetSeverity). Fields not set by the options are inherited
+// from any TypedError already in the chain or, if the options set a new type,
+// taken from the defaults of the type, as the constructors like NotFound do.
+// Typed returns nil if err is nil.
+func Typed(err error, errOpts ...ErrOption) error {
+	if err == nil {
+		return nil
+	}
+
+	te := &TypedError{
+		err: err,
+	}
+	if spec, ok := SpecOf(err); ok {
+		te.Spec = spec
+	}
+	te.Spec.Message = Cause(err).Error()
+
+	var set ErrorSpec
+	set.setOptions(errOpts...)
+	if len(set.Type) > 0 && set.Type != te.Spec.Type {
+		set.setDefaults()
+	}
+	te.Spec.inherit(set)
+	if len(te.Spec.Type) > 0 {
+		te.Spec.setDefaults()
+	}
+
+	te.Spec.Message = te.Spec.localizedMessage(DefaultLanguage)
+
+	return te
+}
+
+// SpecOf returns the ErrorSpec of the first TypedError in err's chain.
+func SpecOf(err error) (ErrorSpec, bool) {
+	var te *TypedError
+	if !As(err, &te) {
+		return ErrorSpec{}, false
+	}
+
+	return te.Spec, true
+}
+
+// IsType reports whether any TypedError in err's chain has type t.
+func IsType(err error, t string) bool {
+	return Is(err, &TypedError{Spec: ErrorSpec{Type: t}})
+}
+
+func (e *TypedError) Error() string {
+	if e.err == nil {
+		return e.Spec.Message
+	}
+
+	return e.err.Error()
+}
+
+func (e *TypedError) Unwrap() error {
+	return e.err
+}
+
+// Cause implements the github.com/pkg/errors causer interface.
+func (e *TypedError) Cause() error {
+	return e.err
+}
+
+// Is reports whether target is a *TypedError whose non-zero spec fields
+// (Type, StatusCode, Priority and Severity) all match e.
+func (e *TypedError) Is(target error) bool {
+	t, ok := target.(*TypedError)
+	if !ok {
+		return false
+	}
+
+	if t.Spec.Type == "" && t.Spec.StatusCode == 0 && t.Spec.Priority == "" && t.Spec.Severity == "" {
+		return false
+	}
+	if t.Spec.Type != "" && t.Spec.Type != e.Spec.Type {
+		return false
+	}
+	if t.Spec.StatusCode != 0 && t.Spec.StatusCode != e.Spec.StatusCode {
+		return false
+	}
+	if t.Spec.Priority != "" && t.Spec.Priority != e.Spec.Priority {
+		return false
+	}
+	if t.Spec.Severity != "" && t.Spec.Severity != e.Spec.Severity {
+		return false
+	}
+
+	return true
+}
+
+func (e *TypedError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') && e.err != nil {
+			fmt.Fprintf(s, "%+v", e.err)
+			return
+		}
+		fallthrough
+	case 's':
+		io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	}
+}
+
+func (s *ErrorSpec) inherit(spec ErrorSpec) {
+	if spec.Code != "" {
+		s.Code = spec.Code
+		s.params = spec.params
+	}
+	if spec.Type != "" {
+		s.Type = spec.Type
+	}
+	if spec.StatusCode != 0 {
+		s.StatusCode = spec.StatusCode
+	}
+	if spec.Priority != "" {
+		s.Priority = spec.Priority
+	}
+	if spec.Severity != "" {
+		s.Severity = spec.Severity
+	}
+	if spec.RequestID != "" {
+		s.RequestID = spec.RequestID
+	}
+	if spec.UserID != "" {
+		s.UserID = spec.UserID
+	}
+	if spec.Tenant != "" {
+		s.Tenant = spec.Tenant
+	}
+	if spec.TraceID != "" {
+		s.TraceID = spec.TraceID
+		s.SpanID = spec.SpanID
+	}
+}
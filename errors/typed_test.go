@@ -0,0 +1,53 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors_test
+
+import (
+	"net/http"
+	"testing"
+
+	"x6a.dev/pkg/errors"
+	"x6a.dev/pkg/errors/errorstest"
+)
+
+func TestTypedDefaults(t *testing.T) {
+	err := errors.Typed(errors.New("no rows"), errors.SetType(errors.TypeNotFound))
+
+	errorstest.AssertType(t, err, errors.TypeNotFound)
+	errorstest.AssertStatusCode(t, err, http.StatusNotFound)
+
+	spec, _ := errors.SpecOf(err)
+	want := errors.TypeDefaults(errors.TypeNotFound)
+	if spec.Priority != want.Priority || spec.Severity != want.Severity {
+		t.Errorf("got priority %q, severity %q, want the type defaults %q, %q", spec.Priority, spec.Severity, want.Priority, want.Severity)
+	}
+}
+
+func TestTypedOverride(t *testing.T) {
+	err := errors.Typed(errors.New("no rows"), errors.SetType(errors.TypeNotFound), errors.SetStatusCode(http.StatusGone))
+	errorstest.AssertStatusCode(t, err, http.StatusGone)
+
+	// A new type replaces the inherited classification.
+	err = errors.Typed(errors.NotFound("user %v", "alice"), errors.SetType(errors.TypeConflict))
+	errorstest.AssertType(t, err, errors.TypeConflict)
+	errorstest.AssertStatusCode(t, err, http.StatusConflict)
+
+	// The options without a type keep the inherited one.
+	err = errors.Typed(errors.NotFound("user %v", "alice"), errors.SetSeverity(errors.SeverityWarning))
+	errorstest.AssertType(t, err, errors.TypeNotFound)
+	errorstest.AssertStatusCode(t, err, http.StatusNotFound)
+	errorstest.AssertSeverity(t, err, errors.SeverityWarning)
+}
@@ -0,0 +1,121 @@
+// Copyright © 2019 x6a
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package errors
+
+import (
+	"fmt"
+	"net/http"
+)
+
+var typeDefaults = map[string]ErrorSpec{
+	TypeOK:                 {StatusCode: http.StatusOK, Priority: PriorityLow, Severity: SeverityInfo},
+	TypeInternalError:      {StatusCode: http.StatusInternalServerError, Priority: PriorityMedium, Severity: SeverityError},
+	TypeConfigurationError: {StatusCode: http.StatusInternalServerError, Priority: PriorityHigh, Severity: SeverityError},
+	TypeSecurity:           {StatusCode: http.StatusForbidden, Priority: PriorityHigh, Severity: SeverityWarning},
+	TypeInvalidData:        {StatusCode: http.StatusBadRequest, Priority: PriorityLow, Severity: SeverityWarning},
+	TypeNetworkUnreachable: {StatusCode: http.StatusServiceUnavailable, Priority: PriorityHigh, Severity: SeverityError},
+	TypeNotFound:           {StatusCode: http.StatusNotFound, Priority: PriorityLow, Severity: SeverityInfo},
+	TypeConflict:           {StatusCode: http.StatusConflict, Priority: PriorityLow, Severity: SeverityWarning},
+	TypeUnauthorized:       {StatusCode: http.StatusUnauthorized, Priority: PriorityMedium, Severity: SeverityWarning},
+	TypeForbidden:          {StatusCode: http.StatusForbidden, Priority: PriorityMedium, Severity: SeverityWarning},
+	TypeRateLimited:        {StatusCode: http.StatusTooManyRequests, Priority: PriorityLow, Severity: SeverityWarning},
+	TypeTimeout:            {StatusCode: http.StatusGatewayTimeout, Priority: PriorityMedium, Severity: SeverityError},
+	TypeUnavailable:        {StatusCode: http.StatusServiceUnavailable, Priority: PriorityHigh, Severity: SeverityError},
+	TypeCanceled:           {StatusCode: StatusClientClosedRequest, Priority: PriorityLow, Severity: SeverityInfo},
+	TypeUnimplemented:      {StatusCode: http.StatusNotImplemented, Priority: PriorityMedium, Severity: SeverityError},
+}
+
+// TypeDefaults returns the default status code, priority and severity of the
+// error type t. Unknown types get the TypeInternalError defaults.
+func TypeDefaults(t string) ErrorSpec {
+	spec, ok := typeDefaults[t]
+	if !ok {
+		spec = typeDefaults[TypeInternalError]
+	}
+	spec.Type = t
+
+	return spec
+}
+
+// setDefaults fills the zero Type, StatusCode, Priority and Severity of s
+// with the defaults of its type.
+func (s *ErrorSpec) setDefaults() {
+	if len(s.Type) == 0 {
+		s.Type = TypeInternalError
+	}
+
+	defaults := TypeDefaults(s.Type)
+	if s.StatusCode == 0 {
+		s.StatusCode = defaults.StatusCode
+	}
+	if len(s.Priority) == 0 {
+		s.Priority = defaults.Priority
+	}
+	if len(s.Severity) == 0 {
+		s.Severity = defaults.Severity
+	}
+}
+
+// newTypedf returns a *TypedError of type t with the type defaults, recording
+// the stack of the caller of the exported constructor.
+func newTypedf(t, format string, args ...interface{}) error {
+	te := &TypedError{
+		Spec: TypeDefaults(t),
+		err: &stackError{
+			msg:   fmt.Sprintf(format, args...),
+			stack: callers(2),
+		},
+	}
+	te.Spec.Message = te.err.Error()
+
+	return te
+}
+
+func NotFound(format string, args ...interface{}) error {
+	return newTypedf(TypeNotFound, format, args...)
+}
+
+func Conflict(format string, args ...interface{}) error {
+	return newTypedf(TypeConflict, format, args...)
+}
+
+func Unauthorized(format string, args ...interface{}) error {
+	return newTypedf(TypeUnauthorized, format, args...)
+}
+
+func Forbidden(format string, args ...interface{}) error {
+	return newTypedf(TypeForbidden, format, args...)
+}
+
+func RateLimited(format string, args ...interface{}) error {
+	return newTypedf(TypeRateLimited, format, args...)
+}
+
+func Timeout(format string, args ...interface{}) error {
+	return newTypedf(TypeTimeout, format, args...)
+}
+
+func Unavailable(format string, args ...interface{}) error {
+	return newTypedf(TypeUnavailable, format, args...)
+}
+
+func Canceled(format string, args ...interface{}) error {
+	return newTypedf(TypeCanceled, format, args...)
+}
+
+func Unimplemented(format string, args ...interface{}) error {
+	return newTypedf(TypeUnimplemented, format, args...)
+}
@@ -29,6 +29,14 @@ import (
 	"x6a.dev/pkg/errors"
 )
 
+var connectRetryOptions = func() errors.RetryOptions {
+	opts := errors.ConnectRetryOptions
+	opts.OnRetry = func(err error, wait time.Duration) {
+		fmt.Printf("WARNING: unable to connect to etcd, retrying in %v..\n", wait.Round(time.Millisecond))
+	}
+	return opts
+}()
+
 var EtcdClient *clientv3.Client
 var Close = make(chan struct{})
 
@@ -46,11 +54,11 @@ func NewClient(etcdEndpoint, etcdUsername, etcdPassword, etcdCACertB64 string) (
 	if len(etcdCACertB64) > 0 {
 		blob, err := base64.URLEncoding.DecodeString(etcdCACertB64)
 		if err != nil {
-			return nil, errors.Wrapf(err, "[%v] function base64.URLEncoding.DecodeString(etcdCACertB64)", errors.Trace())
+			return nil, errors.Wrapf(err, "function base64.URLEncoding.DecodeString(etcdCACertB64)")
 		}
 		certs = x509.NewCertPool()
 		if ok := certs.AppendCertsFromPEM(blob); !ok {
-			return nil, errors.Wrapf(err, "[%v] function certs.AppendCertsFromPEM(blob)", errors.Trace())
+			return nil, errors.Wrapf(err, "function certs.AppendCertsFromPEM(blob)")
 		}
 
 		etcdCfg = clientv3.Config{
@@ -80,14 +88,14 @@ func NewClient(etcdEndpoint, etcdUsername, etcdPassword, etcdCACertB64 string) (
 		}
 	}
 
-	c, err := clientv3.New(etcdCfg)
-	for i := 0; err != nil && i < 10; i++ {
-		fmt.Println("WARNING: unable to connect to etcd, retrying in 3s..")
-		time.Sleep(3 * time.Second)
+	var c *clientv3.Client
+	err := errors.Retry(context.Background(), &connectRetryOptions, func() error {
+		var err error
 		c, err = clientv3.New(etcdCfg)
-	}
+		return err
+	})
 	if err != nil {
-		return nil, errors.Wrapf(err, "[%v] function clientv3.New(etcdCfg): unable to etcd db", errors.Trace())
+		return nil, errors.Wrapf(err, "function clientv3.New(etcdCfg): unable to etcd db")
 	}
 
 	return c, nil
@@ -113,7 +121,7 @@ func Put(k, v string) error {
 	_, err := EtcdClient.Put(ctx, k, v)
 	if err != nil {
 		etcdErrorHandler(err)
-		return errors.Wrapf(err, "[%v] function EtcdClient.Put(ctx, k, v)", errors.Trace())
+		return errors.Wrapf(err, "function EtcdClient.Put(ctx, k, v)")
 	}
 
 	return nil
@@ -126,7 +134,7 @@ func Get(k string) (string, error) {
 	resp, err := EtcdClient.Get(ctx, k)
 	if err != nil {
 		etcdErrorHandler(err)
-		return "", errors.Wrapf(err, "[%v] function EtcdClient.Get(ctx, k)", errors.Trace())
+		return "", errors.Wrapf(err, "function EtcdClient.Get(ctx, k)")
 	}
 
 	return string(resp.Kvs[0].Value), nil
@@ -139,7 +147,7 @@ func Delete(k string) error {
 	_, err := EtcdClient.Delete(ctx, k)
 	if err != nil {
 		etcdErrorHandler(err)
-		return errors.Wrapf(err, "[%v] function EtcdClient.Delete(ctx, k)", errors.Trace())
+		return errors.Wrapf(err, "function EtcdClient.Delete(ctx, k)")
 	}
 
 	return nil
@@ -0,0 +1,57 @@
+// Copyright (C) 2019 x6a
+//
+// pkg is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// pkg is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with pkg. If not, see <http://www.gnu.org/licenses/>.
+
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Mail is a plain text email.
+type Mail struct {
+	From    string
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Header returns v fit for a header value, with its line breaks replaced
+// by spaces so it can't inject other headers.
+func Header(v string) string {
+	return strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(v)
+}
+
+// Bytes returns the message, with CRLF line endings, to send with SendMail.
+func (m *Mail) Bytes() []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", Header(m.From))
+	fmt.Fprintf(&msg, "To: %s\r\n", Header(strings.Join(m.To, ", ")))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", Header(m.Subject))
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n")
+	fmt.Fprintf(&msg, "\r\n")
+
+	msg.WriteString(strings.NewReplacer("\r\n", "\r\n", "\r", "\r\n", "\n", "\r\n").Replace(m.Body))
+
+	return msg.Bytes()
+}
+
+// Send mails m through the SMTP server at addr, see SendMail.
+func (m *Mail) Send(addr string, a smtp.Auth, timeout time.Duration) error {
+	return SendMail(addr, a, m.From, m.To, m.Bytes(), timeout)
+}
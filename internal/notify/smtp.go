@@ -0,0 +1,94 @@
+// Copyright (C) 2019 x6a
+//
+// pkg is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// pkg is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with pkg. If not, see <http://www.gnu.org/licenses/>.
+
+// Package notify implements the delivery shared by the notifiers of the
+// errors and xlog packages.
+package notify
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTPTimeout bounds the whole exchange with the SMTP server.
+const SMTPTimeout = 30 * time.Second
+
+// SendMail works like smtp.SendMail, failing if the exchange with the server
+// takes longer than timeout (SMTPTimeout if zero).
+func SendMail(addr string, a smtp.Auth, from string, to []string, msg []byte, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = SMTPTimeout
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("function net.SplitHostPort(): %w", err)
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return fmt.Errorf("function net.Dial(): %w", err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return fmt.Errorf("function conn.SetDeadline(): %w", err)
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("function smtp.NewClient(): %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("function c.StartTLS(): %w", err)
+		}
+	}
+	if a != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(a); err != nil {
+			return fmt.Errorf("function c.Auth(): %w", err)
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return fmt.Errorf("function c.Mail(): %w", err)
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("function c.Rcpt(): %w", err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("function c.Data(): %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("function w.Write(): %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("function w.Close(): %w", err)
+	}
+
+	return c.Quit()
+}
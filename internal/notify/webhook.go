@@ -0,0 +1,95 @@
+// Copyright (C) 2019 x6a
+//
+// pkg is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// pkg is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with pkg. If not, see <http://www.gnu.org/licenses/>.
+
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// WebhookTimeout is the timeout of the default webhook client.
+	WebhookTimeout = 10 * time.Second
+
+	// MaxRetryAfter caps the Retry-After delay waited for.
+	MaxRetryAfter = 30 * time.Second
+
+	// maxWebhookRetries is the number of times a request answered with 429
+	// Too Many Requests is retried.
+	maxWebhookRetries = 3
+)
+
+// PostWebhook posts body to url, waiting and retrying as long as the server
+// answers 429 Too Many Requests with a Retry-After delay. A nil client uses
+// one with WebhookTimeout.
+func PostWebhook(client *http.Client, url, contentType string, body []byte) error {
+	if client == nil {
+		client = &http.Client{Timeout: WebhookTimeout}
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := client.Post(url, contentType, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("function client.Post(): %w", err)
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt <= maxWebhookRetries {
+			time.Sleep(RetryAfter(resp.Header.Get("Retry-After")))
+			continue
+		}
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("[%s] Error posting to webhook", resp.Status)
+		}
+
+		return nil
+	}
+}
+
+// RetryAfter parses a Retry-After header value, in seconds (fractional ones
+// included, as Discord sends them) or as an HTTP date, defaulting to 1
+// second and capped to MaxRetryAfter.
+func RetryAfter(v string) time.Duration {
+	if secs, err := strconv.ParseFloat(v, 64); err == nil && secs > 0 {
+		return CapRetryAfter(time.Duration(secs * float64(time.Second)))
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return CapRetryAfter(d)
+		}
+	}
+
+	return time.Second
+}
+
+// CapRetryAfter returns the Retry-After delay d capped to MaxRetryAfter, or 1
+// second if not positive.
+func CapRetryAfter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return time.Second
+	}
+	if d > MaxRetryAfter {
+		return MaxRetryAfter
+	}
+
+	return d
+}
@@ -0,0 +1,38 @@
+// Copyright (C) 2019 x6a
+//
+// pkg is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// pkg is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with pkg. If not, see <http://www.gnu.org/licenses/>.
+
+package notify
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	for v, want := range map[string]time.Duration{
+		"":        time.Second,
+		"0":       time.Second,
+		"2":       2 * time.Second,
+		"0.5":     500 * time.Millisecond,
+		"3600":    MaxRetryAfter,
+		"invalid": time.Second,
+		time.Now().Add(time.Hour).UTC().Format(http.TimeFormat): MaxRetryAfter,
+	} {
+		if got := RetryAfter(v); got != want {
+			t.Errorf("RetryAfter(%q) = %v, want %v", v, got, want)
+		}
+	}
+}
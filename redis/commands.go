@@ -22,12 +22,12 @@ import (
 
 func redisFlushInt(c redis.Conn) (int, error) {
 	if err := c.Flush(); err != nil {
-		return -1, errors.Wrapf(err, "[%v] function c.Flush()", errors.Trace())
+		return -1, errors.Wrapf(err, "function c.Flush()")
 	}
 
 	reply, err := redis.Int(c.Receive())
 	if err != nil {
-		return -1, errors.Wrapf(err, "[%v] function c.Receive()", errors.Trace())
+		return -1, errors.Wrapf(err, "function c.Receive()")
 	}
 
 	return reply, nil
@@ -39,7 +39,7 @@ func RedisHMSet(h string, m map[string]string) (string, error) {
 
 	reply, err := redis.String(c.Do("HMSET", redis.Args{}.Add(h).AddFlat(m)...))
 	if err != nil {
-		return "", errors.Wrapf(err, "[%v] error from redis cmd HMSET hash (%v), key-values (%v)", errors.Trace(), h, m)
+		return "", errors.Wrapf(err, "error from redis cmd HMSET hash (%v), key-values (%v)", h, errors.Sensitive(m))
 	}
 	return reply, nil
 }
@@ -50,7 +50,7 @@ func RedisHGetAll(h string) (map[string]string, error) {
 
 	reply, err := redis.StringMap(c.Do("HGETALL", h))
 	if err != nil {
-		return nil, errors.Wrapf(err, "[%v] error from redis cmd HGETALL hash (%v)", errors.Trace(), h)
+		return nil, errors.Wrapf(err, "error from redis cmd HGETALL hash (%v)", h)
 	}
 	return reply, nil
 }
@@ -61,7 +61,7 @@ func RedisHGet(h, k string) (string, error) {
 
 	reply, err := redis.String(c.Do("HGET", h, k))
 	if err != nil {
-		return "", errors.Wrapf(err, "[%v] error from redis cmd HGET hash (%v), key (%v)", errors.Trace(), h, k)
+		return "", errors.Wrapf(err, "error from redis cmd HGET hash (%v), key (%v)", h, k)
 	}
 	return reply, nil
 }
@@ -72,7 +72,7 @@ func RedisSAdd(s, v string) (int, error) {
 
 	reply, err := redis.Int(c.Do("SADD", s, v))
 	if err != nil {
-		return -1, errors.Wrapf(err, "[%v] error from redis cmd SADD set (%v), value (%v)", errors.Trace(), s, v)
+		return -1, errors.Wrapf(err, "error from redis cmd SADD set (%v), value (%v)", s, v)
 	}
 	return reply, nil
 }
@@ -83,7 +83,7 @@ func RedisSMAdd(s string, v ...string) (int, error) {
 
 	for _, i := range v {
 		if err := c.Send("SADD", s, i); err != nil {
-			return -1, errors.Wrapf(err, "[%v] error from redis cmd SREM set (%v), value (%v)", errors.Trace(), s, i)
+			return -1, errors.Wrapf(err, "error from redis cmd SREM set (%v), value (%v)", s, i)
 		}
 	}
 
@@ -96,7 +96,7 @@ func RedisSRem(s, v string) (int, error) {
 
 	reply, err := redis.Int(c.Do("SREM", s, v))
 	if err != nil {
-		return -1, errors.Wrapf(err, "[%v] error from redis cmd SREM set (%v), value (%v)", errors.Trace(), s, v)
+		return -1, errors.Wrapf(err, "error from redis cmd SREM set (%v), value (%v)", s, v)
 	}
 	return reply, nil
 }
@@ -107,7 +107,7 @@ func RedisSMRem(s string, v ...string) (int, error) {
 
 	for _, i := range v {
 		if err := c.Send("SREM", s, i); err != nil {
-			return -1, errors.Wrapf(err, "[%v] error from redis cmd SREM set (%v), value (%v)", errors.Trace(), s, i)
+			return -1, errors.Wrapf(err, "error from redis cmd SREM set (%v), value (%v)", s, i)
 		}
 	}
 
@@ -120,7 +120,7 @@ func RedisSPop(s string) (string, error) {
 
 	reply, err := redis.String(c.Do("SPOP", s))
 	if err != nil {
-		return "", errors.Wrapf(err, "[%v] error from redis cmd SPOP set (%v)", errors.Trace(), s)
+		return "", errors.Wrapf(err, "error from redis cmd SPOP set (%v)", s)
 	}
 	if reply == "nil" {
 		reply = ""
@@ -134,7 +134,7 @@ func RedisGet(k string) (string, error) {
 
 	reply, err := redis.String(c.Do("GET", k))
 	if err != nil {
-		return "", errors.Wrapf(err, "[%v] error from redis cmd GET key (%v)", errors.Trace(), k)
+		return "", errors.Wrapf(err, "error from redis cmd GET key (%v)", k)
 	}
 	return reply, nil
 }
@@ -145,7 +145,7 @@ func RedisSet(k, v string) (string, error) {
 
 	reply, err := redis.String(c.Do("SET", k, v))
 	if err != nil {
-		return "", errors.Wrapf(err, "[%v] error from redis cmd SET key (%v), value (%v)", errors.Trace(), k, v)
+		return "", errors.Wrapf(err, "error from redis cmd SET key (%v), value (%v)", k, errors.Sensitive(v))
 	}
 	return reply, nil
 }
@@ -156,7 +156,7 @@ func RedisDel(k string) (int, error) {
 
 	reply, err := redis.Int(c.Do("DEL", k))
 	if err != nil {
-		return -1, errors.Wrapf(err, "[%v] error from redis cmd DEL key (%v)", errors.Trace(), k)
+		return -1, errors.Wrapf(err, "error from redis cmd DEL key (%v)", k)
 	}
 	return reply, nil
 }
@@ -167,7 +167,7 @@ func RedisSMembers(s string) ([]string, error) {
 
 	reply, err := redis.Strings(c.Do("SMEMBERS", s))
 	if err != nil {
-		return nil, errors.Wrapf(err, "[%v] error from redis cmd SMEMBERS set (%v)", errors.Trace(), s)
+		return nil, errors.Wrapf(err, "error from redis cmd SMEMBERS set (%v)", s)
 	}
 	return reply, nil
 }
@@ -178,7 +178,7 @@ func RedisSISMember(s, v string) (bool, error) {
 
 	reply, err := redis.Bool(c.Do("SISMEMBER", s, v))
 	if err != nil {
-		return false, errors.Wrapf(err, "[%v] error from redis cmd SISMEMBER set (%v), value (%v)", errors.Trace(), s, v)
+		return false, errors.Wrapf(err, "error from redis cmd SISMEMBER set (%v), value (%v)", s, v)
 	}
 	return reply, nil
 }
@@ -189,7 +189,7 @@ func RedisExists(k string) (bool, error) {
 
 	reply, err := redis.Bool(c.Do("EXISTS", k))
 	if err != nil {
-		return false, errors.Wrapf(err, "[%v] error from redis cmd EXISTS key (%v)", errors.Trace(), k)
+		return false, errors.Wrapf(err, "error from redis cmd EXISTS key (%v)", k)
 	}
 	return reply, nil
 }
@@ -200,7 +200,7 @@ func RedisGetSet(k, v string) (string, error) {
 
 	reply, err := c.Do("GETSET", k, v)
 	if err != nil {
-		return "", errors.Wrapf(err, "[%v] error from redis cmd GETSET key (%v), value (%v)", errors.Trace(), k, v)
+		return "", errors.Wrapf(err, "error from redis cmd GETSET key (%v), value (%v)", k, errors.Sensitive(v))
 	}
 
 	var r string
@@ -16,6 +16,7 @@
 package redis
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -23,6 +24,14 @@ import (
 	"x6a.dev/pkg/errors"
 )
 
+var connectRetryOptions = func() errors.RetryOptions {
+	opts := errors.ConnectRetryOptions
+	opts.OnRetry = func(err error, wait time.Duration) {
+		fmt.Printf("WARNING: unable to connect to redis, retrying in %v..\n", wait.Round(time.Millisecond))
+	}
+	return opts
+}()
+
 var RedisURL string
 var Pool *redis.Pool
 var Close = make(chan struct{})
@@ -50,14 +59,14 @@ func redisConnect() (redis.Conn, error) {
 		return nil, errors.New("redis config not set")
 	}
 
-	c, err := redisDial(RedisURL)
-	for i := 0; err != nil && i < 10; i++ {
-		fmt.Println("WARNING: unable to connect to redis, retrying in 3s..")
-		time.Sleep(3 * time.Second)
+	var c redis.Conn
+	err := errors.Retry(context.Background(), &connectRetryOptions, func() error {
+		var err error
 		c, err = redisDial(RedisURL)
-	}
+		return err
+	})
 	if err != nil {
-		return nil, errors.Wrapf(err, "[%v] unable to connect to redis db", errors.Trace())
+		return nil, errors.Wrapf(err, "unable to connect to redis db")
 	}
 	return c, nil
 }
@@ -31,13 +31,13 @@ func FileToB64(file string) (string, error) {
 	if _, err := os.Stat(file); err == nil {
 		blob, err = ioutil.ReadFile(file)
 		if err != nil {
-			return "", errors.Wrapf(err, "[%v] function ioutil.ReadFile(file)", errors.Trace())
+			return "", errors.Wrapf(err, "function ioutil.ReadFile(file)")
 		}
 	} else if os.IsNotExist(err) {
 		fmt.Printf("file %v not found", file)
-		return "", errors.Wrapf(err, "[%v] file %v not found", errors.Trace(), file)
+		return "", errors.Wrapf(err, "file %v not found", file)
 	} else {
-		return "", errors.Wrapf(err, "[%v] file stat error", errors.Trace())
+		return "", errors.Wrapf(err, "file stat error")
 	}
 
 	return base64.URLEncoding.EncodeToString(blob), nil
@@ -0,0 +1,151 @@
+// Copyright (C) 2019 x6a
+//
+// pkg is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// pkg is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with pkg. If not, see <http://www.gnu.org/licenses/>.
+
+package xlog
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"x6a.dev/pkg/errors"
+)
+
+// Encoding is the output encoding of a sink.
+type Encoding string
+
+const (
+	// EncodingText is the human readable text output, colored if the
+	// output is a terminal.
+	EncodingText Encoding = "text"
+	// EncodingJSON is a JSON object per line, for log shippers.
+	EncodingJSON Encoding = "json"
+)
+
+type jsonRecord struct {
+	Time     string                 `json:"time"`
+	Level    string                 `json:"level"`
+	Severity string                 `json:"severity"`
+	Priority Priority               `json:"priority"`
+	HostID   string                 `json:"hostId,omitempty"`
+	Message  string                 `json:"message"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`
+	Caller   string                 `json:"caller,omitempty"`
+}
+
+// WithEncoding sets the encoding of the messages printed to the output (see
+// WithOutput), EncodingText by default.
+func WithEncoding(enc Encoding) *LogOption {
+	return &LogOption{
+		key:   logOptionEncoding,
+		value: enc,
+	}
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// caller returns the file:line of the function that logged the message,
+// skipping the frames of xlog and of the errors package reporting through
+// it.
+func caller() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "x6a.dev/pkg/xlog.") &&
+			!strings.HasPrefix(frame.Function, "x6a.dev/pkg/errors.") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// jsonValue keeps the numbers and booleans as such, and renders any other
+// value as its redacted text. The values of the sensitive fields are always
+// redacted.
+func jsonValue(f Field) interface{} {
+	if errors.IsRedactedField(f.Key) {
+		return errors.RedactedText
+	}
+
+	switch f.Value.(type) {
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return f.Value
+	}
+
+	return f.String()
+}
+
+func (l *Logger) jsonLine(level LogLevel, timestamp time.Time, msg, caller string) string {
+	r := &jsonRecord{
+		Time:     timestamp.Format(time.RFC3339Nano),
+		Level:    strings.TrimSpace(logPrefixes[level]),
+		Severity: l.severity(level),
+		Priority: l.priority(level),
+		HostID:   l.hostID,
+		Message:  msg,
+		Caller:   caller,
+	}
+
+	if len(l.fields) > 0 {
+		r.Fields = make(map[string]interface{}, len(l.fields))
+		for _, f := range l.fields {
+			r.Fields[f.Key] = jsonValue(f)
+		}
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return msg
+	}
+
+	return string(b)
+}
+
+// textLine renders the message with the level and timestamp prefix,
+// colored if color is set.
+func (l *Logger) textLine(level LogLevel, timestamp time.Time, msg string, color bool) string {
+	line := "[" + logPrefixes[level] + "] " + timestamp.Format(TIME_FORMAT) + " " + msg
+	if color {
+		line = l.logPrefix(level, timestamp) + " " + msg
+	}
+
+	if len(l.fields) > 0 {
+		line += " " + fieldsText(l.fields, color)
+	}
+
+	return line
+}
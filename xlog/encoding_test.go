@@ -0,0 +1,53 @@
+// Copyright (C) 2019 x6a
+//
+// pkg is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// pkg is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with pkg. If not, see <http://www.gnu.org/licenses/>.
+
+package xlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONEncodingRedactsFields(t *testing.T) {
+	var out bytes.Buffer
+	l := New(INFO, "host", WithOutput(&out), WithEncoding(EncodingJSON)).With(
+		Int("otp_token", 123456),
+		Bool("password_set", true),
+		Int("attempts", 3),
+	)
+	l.Info("login")
+
+	var r struct {
+		Message string
+		Fields  map[string]interface{}
+	}
+	if err := json.Unmarshal(out.Bytes(), &r); err != nil {
+		t.Fatalf("json.Unmarshal(%s) = %v", out.Bytes(), err)
+	}
+
+	if r.Message != "login" {
+		t.Errorf("got message %q, want %q", r.Message, "login")
+	}
+	for key, want := range map[string]interface{}{
+		"otp_token":    "[REDACTED]",
+		"password_set": "[REDACTED]",
+		"attempts":     float64(3),
+	} {
+		if r.Fields[key] != want {
+			t.Errorf("got %v = %v, want %v", key, r.Fields[key], want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright (C) 2019 x6a
+//
+// pkg is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// pkg is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with pkg. If not, see <http://www.gnu.org/licenses/>.
+
+package xlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"x6a.dev/pkg/colors"
+	"x6a.dev/pkg/errors"
+)
+
+// Field is a key/value pair attached to the messages of a Logger (see With).
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+func String(key, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Int(key string, value int) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Float64(key string, value float64) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Bool(key string, value bool) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Err returns an "error" field holding the message of err.
+func Err(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// String returns the redacted text of the field value.
+func (f Field) String() string {
+	var v string
+
+	switch value := f.Value.(type) {
+	case nil:
+		v = ""
+	case string:
+		v = value
+	case error:
+		v = value.Error()
+	case time.Time:
+		v = value.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		v = value.String()
+	default:
+		v = fmt.Sprint(value)
+	}
+
+	// The whole value of the sensitive fields (e.g. "password") is
+	// redacted, and the secrets found in any other.
+	if errors.IsRedactedField(f.Key) {
+		return errors.RedactedText
+	}
+
+	return errors.Redact(v)
+}
+
+// MarshalJSON renders the field with its redacted value, keeping the numbers
+// and booleans as such, so the fields posted as JSON (e.g. by a
+// WebhookNotifier) don't leak secrets.
+func (f Field) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Key   string
+		Value interface{}
+	}{
+		Key:   f.Key,
+		Value: jsonValue(f),
+	})
+}
+
+// With returns a child of l adding fields to all its messages, after the
+// fields of l.
+func (l *Logger) With(fields ...Field) *Logger {
+	child := l.Child()
+	child.fields = append(append(make([]Field, 0, len(l.fields)+len(fields)), l.fields...), fields...)
+
+	return child
+}
+
+// With returns a child of the default Logger adding fields to all its
+// messages.
+func With(fields ...Field) *Logger {
+	return Default().With(fields...)
+}
+
+// Fields returns the fields l adds to its messages.
+func (l *Logger) Fields() []Field {
+	return append([]Field(nil), l.fields...)
+}
+
+// fieldsText renders the fields in the key=value form, quoting the values
+// when needed.
+func fieldsText(fields []Field, color bool) string {
+	pairs := make([]string, len(fields))
+	for i, f := range fields {
+		key := f.Key
+		if color {
+			key = colors.White(key)
+		}
+
+		v := f.String()
+		if len(v) == 0 || strings.ContainsAny(v, " \t\n\"=") {
+			v = strconv.Quote(v)
+		}
+
+		pairs[i] = key + "=" + v
+	}
+
+	return strings.Join(pairs, " ")
+}
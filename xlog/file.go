@@ -0,0 +1,393 @@
+// Copyright (C) 2019 x6a
+//
+// pkg is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// pkg is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with pkg. If not, see <http://www.gnu.org/licenses/>.
+
+package xlog
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"x6a.dev/pkg/errors"
+)
+
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+type FileOption struct {
+	Level    LogLevel
+	Path     string
+	Encoding Encoding // EncodingText by default, never colored
+
+	// MaxSize rotates the file once it reaches the given size in bytes, and
+	// RotateEvery at every interval boundary (e.g. 24 * time.Hour rotates it
+	// daily at midnight UTC). Zero disables them.
+	MaxSize     int64
+	RotateEvery time.Duration
+
+	// MaxBackups is the number of rotated files kept, all of them if zero.
+	MaxBackups int
+	// Compress gzips the rotated files.
+	Compress bool
+	// ReopenOnSIGHUP reopens the file when the process receives SIGHUP, so
+	// it can be rotated by an external tool like logrotate.
+	ReopenOnSIGHUP bool
+}
+
+type fileSink struct {
+	opt FileOption
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool
+
+	// The rotated backups are milled in order by a single goroutine, running
+	// while pending isn't empty.
+	millMu  sync.Mutex
+	pending []string
+	milling bool
+
+	startOnce sync.Once
+	signals   chan os.Signal
+}
+
+// WithFile writes the messages of opt.Level and above to the opt.Path file,
+// which is created if needed and appended to.
+func WithFile(opt *FileOption) *LogOption {
+	return &LogOption{
+		key: logOptionFile,
+		value: &fileSink{
+			opt: *opt,
+		},
+	}
+}
+
+// start listens for SIGHUP if ReopenOnSIGHUP is set. It is called when the
+// option is applied to a Logger, and only the first call has effect.
+func (s *fileSink) start() {
+	s.startOnce.Do(func() {
+		if !s.opt.ReopenOnSIGHUP {
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.closed {
+			return
+		}
+
+		s.signals = make(chan os.Signal, 1)
+		signal.Notify(s.signals, syscall.SIGHUP)
+		go func(signals <-chan os.Signal) {
+			for range signals {
+				s.reopen()
+			}
+		}(s.signals)
+	})
+}
+
+func (s *fileSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(s.opt.Path), 0755); err != nil {
+		return errors.Wrapf(err, "function os.MkdirAll()")
+	}
+
+	f, err := os.OpenFile(s.opt.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "function os.OpenFile()")
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return errors.Wrapf(err, "function f.Stat()")
+	}
+
+	s.file = f
+	s.size = fi.Size()
+	s.openedAt = fi.ModTime()
+	if fi.Size() == 0 {
+		s.openedAt = time.Now()
+	}
+
+	return nil
+}
+
+func (s *fileSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, errors.Wrapf(os.ErrClosed, "function f.Write(): %v", s.opt.Path)
+	}
+
+	if s.file == nil {
+		if err := s.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if s.shouldRotate(int64(len(p))) {
+		if err := s.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := s.file.Write(p)
+	s.size += int64(n)
+	if err != nil {
+		return n, errors.Wrapf(err, "function f.Write()")
+	}
+
+	return n, nil
+}
+
+func (s *fileSink) shouldRotate(n int64) bool {
+	if s.size == 0 {
+		return false
+	}
+	if s.opt.MaxSize > 0 && s.size+n > s.opt.MaxSize {
+		return true
+	}
+	if s.opt.RotateEvery > 0 {
+		now := time.Now()
+		if !now.Truncate(s.opt.RotateEvery).Equal(s.openedAt.Truncate(s.opt.RotateEvery)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backupName returns the name of the backup rotated at t. The names of the
+// backups rotated within the same millisecond get a counter (e.g.
+// app-2006-01-02T15-04-05.000-1.log), so none is overwritten.
+func (s *fileSink) backupName(t time.Time) string {
+	ext := filepath.Ext(s.opt.Path)
+	prefix := strings.TrimSuffix(s.opt.Path, ext) + "-" + t.UTC().Format(backupTimeFormat)
+
+	name := prefix + ext
+	for n := 1; backupExists(name); n++ {
+		name = prefix + "-" + strconv.Itoa(n) + ext
+	}
+
+	return name
+}
+
+// backupExists reports whether the backup exists, compressed or not.
+func backupExists(name string) bool {
+	for _, path := range []string{name, name + ".gz"} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseBackup returns the rotation time and counter of a backup stamp (see
+// backupName).
+func parseBackup(stamp string) (time.Time, int, bool) {
+	n := 0
+	if i := len(backupTimeFormat); len(stamp) > i && stamp[i] == '-' {
+		var err error
+		if n, err = strconv.Atoi(stamp[i+1:]); err != nil {
+			return time.Time{}, 0, false
+		}
+		stamp = stamp[:i]
+	}
+
+	t, err := time.Parse(backupTimeFormat, stamp)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+
+	return t, n, true
+}
+
+// rotate renames the current file to a timestamped backup and opens a new
+// one. The backup is compressed and the old backups pruned in background, see
+// mill.
+func (s *fileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return errors.Wrapf(err, "function f.Close()")
+	}
+	s.file = nil
+
+	backup := s.backupName(time.Now())
+	if err := os.Rename(s.opt.Path, backup); err != nil {
+		return errors.Wrapf(err, "function os.Rename()")
+	}
+
+	if err := s.open(); err != nil {
+		return err
+	}
+	s.openedAt = time.Now()
+
+	s.millMu.Lock()
+	defer s.millMu.Unlock()
+
+	s.pending = append(s.pending, backup)
+	if !s.milling {
+		s.milling = true
+		go s.millPending()
+	}
+
+	return nil
+}
+
+// millPending mills the pending backups in rotation order, returning once
+// there are none left.
+func (s *fileSink) millPending() {
+	for {
+		s.millMu.Lock()
+		if len(s.pending) == 0 {
+			s.milling = false
+			s.millMu.Unlock()
+			return
+		}
+		backup := s.pending[0]
+		s.pending = s.pending[1:]
+		s.millMu.Unlock()
+
+		s.mill(backup)
+	}
+}
+
+// mill compresses the backup file, if required, and removes the backups
+// exceeding MaxBackups. Backups already removed by an earlier pruning are
+// skipped.
+func (s *fileSink) mill(backup string) {
+	if s.opt.Compress {
+		if err := compressFile(backup); err != nil && !os.IsNotExist(errors.Cause(err)) {
+			Errorf("Unable to compress log file %v: %v", backup, err)
+		}
+	}
+
+	if s.opt.MaxBackups <= 0 {
+		return
+	}
+
+	ext := filepath.Ext(s.opt.Path)
+	prefix := strings.TrimSuffix(s.opt.Path, ext) + "-"
+
+	matches, err := filepath.Glob(prefix + "*" + ext + "*")
+	if err != nil {
+		return
+	}
+
+	type backupFile struct {
+		path string
+		t    time.Time
+		n    int
+	}
+
+	backups := make([]backupFile, 0, len(matches))
+	for _, m := range matches {
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(m, prefix), ".gz"), ext)
+		if t, n, ok := parseBackup(stamp); ok {
+			backups = append(backups, backupFile{path: m, t: t, n: n})
+		}
+	}
+	if len(backups) <= s.opt.MaxBackups {
+		return
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].t.Equal(backups[j].t) {
+			return backups[i].t.Before(backups[j].t)
+		}
+		return backups[i].n < backups[j].n
+	})
+	for _, b := range backups[:len(backups)-s.opt.MaxBackups] {
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			Errorf("Unable to remove log file %v: %v", b.path, err)
+		}
+	}
+}
+
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "function os.Open()")
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "function os.OpenFile()")
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return errors.Wrapf(err, "function io.Copy()")
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return errors.Wrapf(err, "function gz.Close()")
+	}
+	if err := dst.Close(); err != nil {
+		return errors.Wrapf(err, "function f.Close()")
+	}
+
+	return os.Remove(path)
+}
+
+// reopen closes the file, so the next write opens opt.Path again.
+func (s *fileSink) reopen() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+}
+
+// Close closes the file, failing the writes made afterwards.
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed && s.signals != nil {
+		signal.Stop(s.signals)
+		close(s.signals)
+	}
+	s.closed = true
+
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.file.Close()
+	s.file = nil
+	if err != nil {
+		return errors.Wrapf(err, "function f.Close()")
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright (C) 2019 x6a
+//
+// pkg is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// pkg is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with pkg. If not, see <http://www.gnu.org/licenses/>.
+
+package xlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The mill errors are logged to the default Logger.
+	var errOut bytes.Buffer
+	defer SetDefault(Default())
+	SetDefault(New(INFO, "host", WithOutput(&errOut)))
+
+	opt := WithFile(&FileOption{
+		Level:      INFO,
+		Path:       filepath.Join(dir, "app.log"),
+		MaxSize:    64,
+		MaxBackups: 2,
+		Compress:   true,
+	})
+	s := opt.value.(*fileSink)
+
+	for i := 0; i < 50; i++ {
+		if _, err := s.Write([]byte(strings.Repeat("x", 40) + "\n")); err != nil {
+			t.Fatalf("Write() = %v", err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.millMu.Lock()
+		milling := s.milling
+		s.millMu.Unlock()
+		if !milling {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the backups weren't milled")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "app-*"))
+	if len(backups) != 2 {
+		t.Errorf("got backups %v, want 2", backups)
+	}
+	for _, b := range backups {
+		if !strings.HasSuffix(b, ".log.gz") {
+			t.Errorf("got backup %v, want it compressed", b)
+		}
+	}
+	if errOut.Len() > 0 {
+		t.Errorf("got mill errors %q", errOut.String())
+	}
+}
+
+func TestFileWriteAfterClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := WithFile(&FileOption{Path: filepath.Join(dir, "app.log"), ReopenOnSIGHUP: true}).value.(*fileSink)
+	if s.signals != nil {
+		t.Error("got SIGHUP handled before the option is applied")
+	}
+
+	l := New(INFO, "host", WithOutput(ioutil.Discard), &LogOption{key: logOptionFile, value: s})
+	if s.signals == nil {
+		t.Error("got SIGHUP not handled once the option is applied")
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	if _, err := s.Write([]byte("message\n")); err == nil {
+		t.Error("Write() = nil after Close, want an error")
+	}
+}
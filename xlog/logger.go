@@ -17,11 +17,16 @@ package xlog
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mgutz/ansi"
 	"x6a.dev/pkg/colors"
+	"x6a.dev/pkg/errors"
 )
 
 const TIME_FORMAT = "2006-01-02 15:04:05.000"
@@ -49,28 +54,30 @@ const (
 	logOptionSlack = iota
 	logOptionFile
 	logOptionSyslog
+	logOptionOutput
+	logOptionHostID
+	logOptionEncoding
+	logOptionNotifier
 )
 
-type slackLoggerCfg struct {
-	webhook  string
-	user     string
-	icon     string
-	logLevel LogLevel
-	channels map[LogLevel]string
-	colors   map[LogLevel]string
-}
-
 type LogOption struct {
 	key   int
 	value interface{}
 }
 
-type logger struct {
-	logLevel LogLevel
+// Logger writes leveled messages to its sinks. A Logger is safe for
+// concurrent use.
+type Logger struct {
+	logLevel int32 // LogLevel, accessed atomically
 	hostID   string
-
-	slackLogger *slackLoggerCfg
-	outputFile  string
+	fields   []Field
+
+	out       *syncWriter
+	encoding  Encoding
+	color     bool
+	notifiers []*notifierSink
+	file      *fileSink
+	syslog    *syslogSink
 }
 
 var logPrefixes = map[LogLevel]string{
@@ -100,18 +107,126 @@ var logColorFuncs = map[LogLevel]func(string) string{
 	ALERT: ansi.ColorFunc("white+bh:red"),
 }
 
-var l = &logger{
-	logLevel: INFO,
+var slackColors = map[LogLevel]string{
+	TRACE: "#ff77ff",
+	DEBUG: "#444999",
+	INFO:  "#009999",
+	WARN:  "#fff000",
+	ERROR: "#ff4444",
+	ALERT: "#990000",
 }
 
-func SetLogger(level LogLevel, hostID string, logOpts ...*LogOption) {
-	logger := &logger{
-		logLevel: level,
+// syncWriter serializes the writes of the loggers sharing a writer.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.w.Write(p)
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultLogger = New(INFO, "")
+)
+
+// New returns a Logger writing the messages of the given level and above to
+// stdout, and to the sinks configured by logOpts.
+func New(level LogLevel, hostID string, logOpts ...*LogOption) *Logger {
+	l := &Logger{
+		logLevel: int32(level),
 		hostID:   hostID,
+		out:      &syncWriter{w: os.Stdout},
+		encoding: EncodingText,
+		color:    isTerminal(os.Stdout),
+	}
+	l.setOptions(logOpts...)
+
+	return l
+}
+
+// Child returns a copy of l sharing its level, hostID and sinks, with logOpts
+// applied on top. Changing the level of the child doesn't affect l.
+func (l *Logger) Child(logOpts ...*LogOption) *Logger {
+	child := &Logger{
+		logLevel: int32(l.Level()),
+		hostID:   l.hostID,
+		fields:   l.fields,
+		out:      l.out,
+		encoding: l.encoding,
+		color:    l.color,
+
+		notifiers: l.notifiers,
+		file:      l.file,
+		syslog:    l.syslog,
+	}
+	child.setOptions(logOpts...)
+
+	return child
+}
+
+// Level returns the minimum level of the messages written by l.
+func (l *Logger) Level() LogLevel {
+	return LogLevel(atomic.LoadInt32(&l.logLevel))
+}
+
+// SetLevel sets the minimum level of the messages written by l.
+func (l *Logger) SetLevel(level LogLevel) {
+	atomic.StoreInt32(&l.logLevel, int32(level))
+}
+
+// HostID returns the host identifier l adds to its messages.
+func (l *Logger) HostID() string {
+	return l.hostID
+}
+
+// Default returns the Logger used by the package-level functions.
+func Default() *Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+
+	return defaultLogger
+}
+
+// SetDefault replaces the Logger used by the package-level functions.
+func SetDefault(l *Logger) {
+	if l == nil {
+		return
+	}
+
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	defaultLogger = l
+}
+
+// SetLogger replaces the default Logger with a new one, see New. The replaced
+// Logger isn't closed, as it may still be in use through its children: its
+// owner releases it with Close.
+func SetLogger(level LogLevel, hostID string, logOpts ...*LogOption) {
+	SetDefault(New(level, hostID, logOpts...))
+}
+
+// WithHostID sets the host identifier added to the messages, to give a child
+// Logger its own (see Child).
+func WithHostID(hostID string) *LogOption {
+	return &LogOption{
+		key:   logOptionHostID,
+		value: hostID,
 	}
-	logger.setOptions(logOpts...)
+}
 
-	l = logger
+// WithOutput sets the writer the messages are printed to, stdout by default.
+// The text output is only colored if the writer is a terminal.
+func WithOutput(w io.Writer) *LogOption {
+	return &LogOption{
+		key:   logOptionOutput,
+		value: w,
+	}
 }
 
 type SlackOption struct {
@@ -125,33 +240,45 @@ type SlackOption struct {
 	WarnChannel  string
 	ErrorChannel string
 	AlertChannel string
+
+	// Delivery options, see NotifierOption.
+	QueueSize      int
+	BatchSize      int
+	BatchWait      time.Duration
+	DropPolicy     DropPolicy
+	CoalesceWindow time.Duration
+	RateLimit      int
+	RateInterval   time.Duration
 }
 
+// WithSlack posts the messages of opt.Level and above to the Slack channel
+// configured for their level, if any, through the opt.Webhook incoming
+// webhook.
 func WithSlack(opt *SlackOption) *LogOption {
+	n := &slackWebhook{
+		webhook: opt.Webhook,
+		user:    opt.User,
+		icon:    opt.Icon,
+	}
+
 	return &LogOption{
 		key: logOptionSlack,
-		value: &slackLoggerCfg{
-			webhook:  opt.Webhook,
-			user:     opt.User,
-			icon:     opt.Icon,
-			logLevel: opt.Level,
-			channels: map[LogLevel]string{
-				TRACE: opt.TraceChannel,
-				DEBUG: opt.DebugChannel,
-				INFO:  opt.InfoChannel,
-				WARN:  opt.WarnChannel,
-				ERROR: opt.ErrorChannel,
-				ALERT: opt.AlertChannel,
-			},
-			colors: map[LogLevel]string{
-				TRACE: "#ff77ff",
-				DEBUG: "#444999",
-				INFO:  "#009999",
-				WARN:  "#fff000",
-				ERROR: "#ff4444",
-				ALERT: "#990000",
-			},
-		},
+		value: newNotifierSink(n, "Slack", &NotifierOption{
+			Level:          opt.Level,
+			TraceChannel:   opt.TraceChannel,
+			DebugChannel:   opt.DebugChannel,
+			InfoChannel:    opt.InfoChannel,
+			WarnChannel:    opt.WarnChannel,
+			ErrorChannel:   opt.ErrorChannel,
+			AlertChannel:   opt.AlertChannel,
+			QueueSize:      opt.QueueSize,
+			BatchSize:      opt.BatchSize,
+			BatchWait:      opt.BatchWait,
+			DropPolicy:     opt.DropPolicy,
+			CoalesceWindow: opt.CoalesceWindow,
+			RateLimit:      opt.RateLimit,
+			RateInterval:   opt.RateInterval,
+		}),
 	}
 }
 
@@ -182,117 +309,231 @@ func (ll LogLevel) String() string {
 	return logPrefixes[ll]
 }
 
-func (l *logger) setOptions(logOpts ...*LogOption) {
+func (l *Logger) setOptions(logOpts ...*LogOption) {
 	for _, opt := range logOpts {
 		switch opt.key {
-		case logOptionSlack:
-			l.slackLogger = opt.value.(*slackLoggerCfg)
+		case logOptionOutput:
+			w := opt.value.(io.Writer)
+			l.out = &syncWriter{w: w}
+			l.color = isTerminal(w)
+		case logOptionEncoding:
+			l.encoding = opt.value.(Encoding)
+		case logOptionHostID:
+			l.hostID = opt.value.(string)
+		case logOptionSlack, logOptionNotifier:
+			sink := opt.value.(*notifierSink)
+			sink.start()
+			l.notifiers = append(l.notifiers[:len(l.notifiers):len(l.notifiers)], sink)
 		case logOptionFile:
-			l.outputFile = opt.value.(string)
+			l.file = opt.value.(*fileSink)
+			l.file.start()
+		case logOptionSyslog:
+			l.syslog = opt.value.(*syslogSink)
 		}
 	}
 }
 
-func (l *logger) logLevelPrefix(level LogLevel) string {
+func (l *Logger) logLevelPrefix(level LogLevel) string {
 	prefix := "[" + logPrefixes[level] + "]"
 
 	return logColorFuncs[level](prefix)
 }
 
-func (l *logger) logPrefix(level LogLevel, timestamp time.Time) string {
+func (l *Logger) logPrefix(level LogLevel, timestamp time.Time) string {
 	//hostID := "[" + colors.White(l.hostID) + "]"
 
 	// return l.logLevelPrefix(level) + " " + timestamp + " " + hostID
 	return l.logLevelPrefix(level) + " " + colors.Black(timestamp.Format(TIME_FORMAT))
 }
 
-func (l *logger) severity(level LogLevel) string {
+func (l *Logger) severity(level LogLevel) string {
+	return levelSeverity(level)
+}
+
+func levelSeverity(level LogLevel) string {
 	return strings.ToUpper(strings.TrimSpace(logPrefixes[level]))
 }
 
-func (l *logger) priority(level LogLevel) Priority {
+func (l *Logger) priority(level LogLevel) Priority {
 	return logPriorities[level]
 }
 
-func (l *logger) log(level LogLevel, args ...interface{}) {
-	if level >= l.logLevel {
-		timestamp := time.Now()
+func (l *Logger) print(level LogLevel, timestamp time.Time, msg string) {
+	var callerLine string
+	if l.encoding == EncodingJSON || (l.file != nil && l.file.opt.Encoding == EncodingJSON) {
+		callerLine = caller()
+	}
+
+	l.printOut(level, timestamp, msg, callerLine)
+
+	if l.file != nil && level >= l.file.opt.Level {
+		line := l.textLine(level, timestamp, msg, false)
+		if l.file.opt.Encoding == EncodingJSON {
+			line = l.jsonLine(level, timestamp, msg, callerLine)
+		}
+
+		if _, err := io.WriteString(l.file, line+"\n"); err != nil {
+			l.printOut(level, timestamp, fmt.Sprintf("Unable to write to log file: %v", err), callerLine)
+		}
+	}
+
+	if l.syslog != nil && level >= l.syslog.opt.Level {
+		if err := l.syslog.write(level, timestamp, l.hostID, msg, l.fields); err != nil {
+			l.printOut(level, timestamp, fmt.Sprintf("Unable to write to syslog: %v", err), callerLine)
+		}
+	}
+}
+
+// printOut prints the message to the output in its encoding.
+func (l *Logger) printOut(level LogLevel, timestamp time.Time, msg, callerLine string) {
+	line := l.textLine(level, timestamp, msg, l.color)
+	if l.encoding == EncodingJSON {
+		line = l.jsonLine(level, timestamp, msg, callerLine)
+	}
+
+	io.WriteString(l.out, line+"\n")
+}
+
+// Close releases the sinks of l, which are shared with its children. The
+// messages queued for the notifiers are posted first, see Flush.
+func (l *Logger) Close() error {
+	var errs []error
 
-		all := append([]interface{}{l.logPrefix(level, timestamp)}, args...)
-		fmt.Println(all...)
+	for _, s := range l.notifiers {
+		errs = append(errs, s.close())
+	}
+	if l.file != nil {
+		errs = append(errs, l.file.Close())
+	}
+	if l.syslog != nil {
+		errs = append(errs, l.syslog.Close())
+	}
 
-		if l.slackLogger != nil {
-			if level >= l.slackLogger.logLevel {
-				if err := l.slackLog(level, timestamp, fmt.Sprint(args...)); err != nil {
-					slackErr := fmt.Errorf("Unable to post to Slack: %v", err)
-					fmt.Println(l.logPrefix(level, timestamp), slackErr)
-				}
-			}
+	for _, err := range errs {
+		if err != nil {
+			return err
 		}
 	}
+
+	return nil
 }
 
-func (l *logger) logf(level LogLevel, format string, args ...interface{}) {
-	if level >= l.logLevel {
+func (l *Logger) log(level LogLevel, args ...interface{}) {
+	if level >= l.Level() {
 		timestamp := time.Now()
 
-		fmt.Println(l.logPrefix(level, timestamp), fmt.Sprintf(format, args...))
+		msg := errors.Redact(strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+		l.print(level, timestamp, msg)
 
-		if l.slackLogger != nil {
-			if level >= l.slackLogger.logLevel {
-				if err := l.slackLog(level, timestamp, fmt.Sprintf(format, args...)); err != nil {
-					slackErr := fmt.Errorf("Unable to post to Slack: %v", err)
-					fmt.Println(l.logPrefix(level, timestamp), slackErr)
-				}
-			}
+		if len(l.notifiers) > 0 {
+			l.notify(level, timestamp, errors.Redact(fmt.Sprint(args...)))
 		}
 	}
 }
 
-func Trace(args ...interface{}) {
+func (l *Logger) logf(level LogLevel, format string, args ...interface{}) {
+	if level >= l.Level() {
+		timestamp := time.Now()
+		msg := errors.Redact(fmt.Sprintf(format, args...))
+
+		l.print(level, timestamp, msg)
+
+		l.notify(level, timestamp, msg)
+	}
+}
+
+func (l *Logger) Trace(args ...interface{}) {
 	l.log(TRACE, args...)
 }
 
-func Debug(args ...interface{}) {
+func (l *Logger) Debug(args ...interface{}) {
 	l.log(DEBUG, args...)
 }
 
-func Info(args ...interface{}) {
+func (l *Logger) Info(args ...interface{}) {
 	l.log(INFO, args...)
 }
 
-func Warn(args ...interface{}) {
+func (l *Logger) Warn(args ...interface{}) {
 	l.log(WARN, args...)
 }
 
-func Error(args ...interface{}) {
+func (l *Logger) Error(args ...interface{}) {
 	l.log(ERROR, args...)
 }
 
-func Alert(args ...interface{}) {
+func (l *Logger) Alert(args ...interface{}) {
 	l.log(ALERT, args...)
 }
 
-func Tracef(format string, args ...interface{}) {
+func (l *Logger) Tracef(format string, args ...interface{}) {
 	l.logf(TRACE, format, args...)
 }
 
-func Debugf(format string, args ...interface{}) {
+func (l *Logger) Debugf(format string, args ...interface{}) {
 	l.logf(DEBUG, format, args...)
 }
 
-func Infof(format string, args ...interface{}) {
+func (l *Logger) Infof(format string, args ...interface{}) {
 	l.logf(INFO, format, args...)
 }
 
-func Warnf(format string, args ...interface{}) {
+func (l *Logger) Warnf(format string, args ...interface{}) {
 	l.logf(WARN, format, args...)
 }
 
-func Errorf(format string, args ...interface{}) {
+func (l *Logger) Errorf(format string, args ...interface{}) {
 	l.logf(ERROR, format, args...)
 }
 
-func Alertf(format string, args ...interface{}) {
+func (l *Logger) Alertf(format string, args ...interface{}) {
 	l.logf(ALERT, format, args...)
 }
+
+func Trace(args ...interface{}) {
+	Default().log(TRACE, args...)
+}
+
+func Debug(args ...interface{}) {
+	Default().log(DEBUG, args...)
+}
+
+func Info(args ...interface{}) {
+	Default().log(INFO, args...)
+}
+
+func Warn(args ...interface{}) {
+	Default().log(WARN, args...)
+}
+
+func Error(args ...interface{}) {
+	Default().log(ERROR, args...)
+}
+
+func Alert(args ...interface{}) {
+	Default().log(ALERT, args...)
+}
+
+func Tracef(format string, args ...interface{}) {
+	Default().logf(TRACE, format, args...)
+}
+
+func Debugf(format string, args ...interface{}) {
+	Default().logf(DEBUG, format, args...)
+}
+
+func Infof(format string, args ...interface{}) {
+	Default().logf(INFO, format, args...)
+}
+
+func Warnf(format string, args ...interface{}) {
+	Default().logf(WARN, format, args...)
+}
+
+func Errorf(format string, args ...interface{}) {
+	Default().logf(ERROR, format, args...)
+}
+
+func Alertf(format string, args ...interface{}) {
+	Default().logf(ALERT, format, args...)
+}
@@ -0,0 +1,539 @@
+// Copyright (C) 2019 x6a
+//
+// pkg is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// pkg is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with pkg. If not, see <http://www.gnu.org/licenses/>.
+
+package xlog
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Notifier delivers log messages to a remote service (see WithNotifier).
+type Notifier interface {
+	// Notify posts a batch of messages for the same channel, the one routed
+	// to their level. What a channel is depends on the Notifier: a Slack
+	// channel, a webhook URL, email addresses...
+	Notify(channel string, msgs []*Message) error
+}
+
+// Message is a log message delivered by a Notifier.
+type Message struct {
+	Level     LogLevel
+	Timestamp time.Time
+	HostID    string
+	Channel   string
+	Text      string
+	Fields    []Field
+
+	// Repeated is the number of identical messages coalesced into this one
+	// (see NotifierOption.CoalesceWindow), and Digest the number of messages
+	// summarized by this one (see NotifierOption.RateLimit).
+	Repeated int
+	Digest   int
+}
+
+func (m *Message) Severity() string {
+	return levelSeverity(m.Level)
+}
+
+func (m *Message) Priority() Priority {
+	return logPriorities[m.Level]
+}
+
+// Color returns the color of the message level as an HTML hex code.
+func (m *Message) Color() string {
+	return slackColors[m.Level]
+}
+
+// Title returns the severity, time and hostID of the message.
+func (m *Message) Title() string {
+	if m.Digest > 0 {
+		return "[" + m.Severity() + "] digest of " + strconv.Itoa(m.Digest) + " messages @" + m.HostID
+	}
+
+	title := "[" + m.Severity() + "] " + m.Timestamp.Format(TIME_FORMAT) + " @" + m.HostID
+	if m.Repeated > 0 {
+		title += " (repeated " + strconv.Itoa(m.Repeated) + " times)"
+	}
+
+	return title
+}
+
+// DropPolicy tells which message is dropped when the notifier queue is full.
+type DropPolicy int
+
+const (
+	// DropNewest drops the message being logged.
+	DropNewest DropPolicy = iota
+	// DropOldest drops the oldest queued message to make room for the new
+	// one.
+	DropOldest
+)
+
+type NotifierOption struct {
+	Level        LogLevel
+	TraceChannel string
+	DebugChannel string
+	InfoChannel  string
+	WarnChannel  string
+	ErrorChannel string
+	AlertChannel string
+
+	// The messages are posted in background: they are queued, up to
+	// QueueSize (1024 by default), and posted in batches of up to BatchSize
+	// messages (20 by default, 100 at most) once BatchWait (1 second by
+	// default) has elapsed since the first one was queued. When the queue is
+	// full, DropPolicy tells which message is dropped.
+	QueueSize  int
+	BatchSize  int
+	BatchWait  time.Duration
+	DropPolicy DropPolicy
+
+	// CoalesceWindow, if not zero, posts the first occurrence of a message
+	// and holds the identical ones logged within the window, which are then
+	// posted as a single message with their count.
+	CoalesceWindow time.Duration
+
+	// RateLimit, if not zero, is the number of messages of each level posted
+	// per RateInterval (1 minute by default). The messages exceeding it are
+	// posted as a single digest at the end of the interval.
+	RateLimit    int
+	RateInterval time.Duration
+}
+
+const (
+	defaultNotifyQueueSize = 1024
+	defaultNotifyBatchSize = 20
+	defaultNotifyBatchWait = time.Second
+
+	defaultNotifyRateInterval = time.Minute
+
+	maxNotifyBatchSize = 100
+)
+
+// notifyCloseTimeout bounds the time Logger.Close waits for the queued
+// messages to be posted.
+var notifyCloseTimeout = 10 * time.Second
+
+// NotifierStats counts the messages handled by a notifier sink.
+type NotifierStats struct {
+	Queued  uint64 // messages queued
+	Sent    uint64 // messages posted
+	Dropped uint64 // messages dropped because the queue was full
+	Failed  uint64 // messages the notifier failed to post
+
+	Coalesced uint64 // repeated messages merged into a single one
+	Digested  uint64 // messages over the rate limit posted in a digest
+}
+
+// SlackStats counts the messages handled by a Slack sink.
+type SlackStats = NotifierStats
+
+type notifyEntry struct {
+	logger *Logger
+	msg    *Message
+}
+
+// notifierSink posts the messages queued by the loggers sharing it from a
+// background goroutine, so logging never waits for the remote service.
+type notifierSink struct {
+	// The counters are accessed atomically, and first in the struct so they
+	// are 64-bit aligned on 32-bit platforms.
+	queued         uint64
+	sent           uint64
+	dropped        uint64
+	failed         uint64
+	coalescedCount uint64
+	digested       uint64
+
+	notifier Notifier
+	name     string
+	logLevel LogLevel
+	channels map[LogLevel]string
+
+	batchSize  int
+	batchWait  time.Duration
+	dropPolicy DropPolicy
+
+	// Only accessed by the run goroutine.
+	coalesceWindow time.Duration
+	coalesced      map[string]*coalescedEntry
+	rateLimit      int
+	rateInterval   time.Duration
+	rates          map[LogLevel]*levelRate
+
+	entries chan *notifyEntry
+	flushes chan chan struct{}
+
+	// The run goroutine is started when the option is applied to a Logger,
+	// and stopped by close.
+	startOnce sync.Once
+	closeOnce sync.Once
+	started   int32 // accessed atomically
+	closed    int32 // accessed atomically
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// WithNotifier posts the messages of opt.Level and above through n, to the
+// channel configured for their level, if any.
+func WithNotifier(n Notifier, opt *NotifierOption) *LogOption {
+	return &LogOption{
+		key:   logOptionNotifier,
+		value: newNotifierSink(n, notifierName(n), opt),
+	}
+}
+
+func newNotifierSink(n Notifier, name string, opt *NotifierOption) *notifierSink {
+	s := &notifierSink{
+		notifier: n,
+		name:     name,
+		logLevel: opt.Level,
+		channels: map[LogLevel]string{
+			TRACE: opt.TraceChannel,
+			DEBUG: opt.DebugChannel,
+			INFO:  opt.InfoChannel,
+			WARN:  opt.WarnChannel,
+			ERROR: opt.ErrorChannel,
+			ALERT: opt.AlertChannel,
+		},
+		batchSize:  opt.BatchSize,
+		batchWait:  opt.BatchWait,
+		dropPolicy: opt.DropPolicy,
+		flushes:    make(chan chan struct{}),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+
+		coalesceWindow: opt.CoalesceWindow,
+		coalesced:      make(map[string]*coalescedEntry),
+		rateLimit:      opt.RateLimit,
+		rateInterval:   opt.RateInterval,
+		rates:          make(map[LogLevel]*levelRate),
+	}
+
+	queueSize := opt.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultNotifyQueueSize
+	}
+	s.entries = make(chan *notifyEntry, queueSize)
+
+	if s.batchSize <= 0 {
+		s.batchSize = defaultNotifyBatchSize
+	}
+	if s.batchSize > maxNotifyBatchSize {
+		s.batchSize = maxNotifyBatchSize
+	}
+	if s.batchWait <= 0 {
+		s.batchWait = defaultNotifyBatchWait
+	}
+	if s.rateInterval <= 0 {
+		s.rateInterval = defaultNotifyRateInterval
+	}
+
+	return s
+}
+
+// start runs the goroutine posting the messages, once.
+func (s *notifierSink) start() {
+	s.startOnce.Do(func() {
+		atomic.StoreInt32(&s.started, 1)
+		go s.run()
+	})
+}
+
+// close posts the queued messages and stops the goroutine posting them,
+// waiting up to notifyCloseTimeout in all. The messages logged afterwards
+// are dropped.
+func (s *notifierSink) close() error {
+	var err error
+
+	s.closeOnce.Do(func() {
+		atomic.StoreInt32(&s.closed, 1)
+		if atomic.LoadInt32(&s.started) == 0 {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), notifyCloseTimeout)
+		defer cancel()
+
+		err = s.flush(ctx)
+		close(s.stop)
+
+		// A notifier still posting stops once it returns.
+		select {
+		case <-s.done:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	})
+
+	return err
+}
+
+// notify queues the message for the channels of its level.
+func (l *Logger) notify(level LogLevel, timestamp time.Time, msg string) {
+	for _, s := range l.notifiers {
+		if level < s.logLevel {
+			continue
+		}
+
+		channel := s.channels[level]
+		if len(channel) == 0 {
+			continue
+		}
+
+		s.enqueue(&notifyEntry{
+			logger: l,
+			msg: &Message{
+				Level:     level,
+				Timestamp: timestamp,
+				HostID:    l.hostID,
+				Channel:   channel,
+				Text:      msg,
+				Fields:    l.fields,
+			},
+		})
+	}
+}
+
+func (s *notifierSink) enqueue(e *notifyEntry) {
+	if atomic.LoadInt32(&s.closed) == 1 {
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+
+	select {
+	case s.entries <- e:
+		atomic.AddUint64(&s.queued, 1)
+		return
+	default:
+	}
+
+	if s.dropPolicy == DropOldest {
+		select {
+		case <-s.entries:
+			atomic.AddUint64(&s.dropped, 1)
+		default:
+		}
+
+		select {
+		case s.entries <- e:
+			atomic.AddUint64(&s.queued, 1)
+			return
+		default:
+		}
+	}
+
+	atomic.AddUint64(&s.dropped, 1)
+}
+
+func (s *notifierSink) run() {
+	defer close(s.done)
+
+	var (
+		batch   []*notifyEntry
+		timeout <-chan time.Time
+	)
+
+	post := func() {
+		s.post(batch)
+		batch = nil
+		timeout = nil
+	}
+
+	add := func(entries ...*notifyEntry) {
+		for _, e := range entries {
+			batch = append(batch, e)
+			if len(batch) == 1 {
+				timeout = time.After(s.batchWait)
+			}
+			if len(batch) >= s.batchSize {
+				post()
+			}
+		}
+	}
+
+	receive := func(e *notifyEntry) {
+		add(s.accept(e)...)
+	}
+
+	var tick <-chan time.Time
+	if period := s.tickPeriod(); period > 0 {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case e := <-s.entries:
+			receive(e)
+
+		case <-timeout:
+			post()
+
+		case <-s.stop:
+			return
+
+		case now := <-tick:
+			add(s.expire(now, false)...)
+
+		case done := <-s.flushes:
+		drain:
+			for {
+				select {
+				case e := <-s.entries:
+					receive(e)
+				default:
+					break drain
+				}
+			}
+			add(s.expire(time.Now(), true)...)
+			post()
+			close(done)
+		}
+	}
+}
+
+// post sends the batch as one Notify call per channel, keeping the order of
+// the messages.
+func (s *notifierSink) post(batch []*notifyEntry) {
+	if len(batch) == 0 {
+		return
+	}
+
+	var channels []string
+	byChannel := make(map[string][]*notifyEntry)
+	for _, e := range batch {
+		if _, ok := byChannel[e.msg.Channel]; !ok {
+			channels = append(channels, e.msg.Channel)
+		}
+		byChannel[e.msg.Channel] = append(byChannel[e.msg.Channel], e)
+	}
+
+	for _, channel := range channels {
+		entries := byChannel[channel]
+
+		msgs := make([]*Message, len(entries))
+		for i, e := range entries {
+			msgs[i] = e.msg
+		}
+
+		if err := s.notifier.Notify(channel, msgs); err != nil {
+			atomic.AddUint64(&s.failed, uint64(len(entries)))
+
+			last := entries[len(entries)-1]
+			notifyErr := fmt.Sprintf("Unable to post to %v: %v", s.name, err)
+			last.logger.printOut(last.msg.Level, time.Now(), notifyErr, "")
+			continue
+		}
+
+		atomic.AddUint64(&s.sent, uint64(len(entries)))
+	}
+}
+
+// flush posts the queued messages, waiting for them to be sent until ctx is
+// done.
+func (s *notifierSink) flush(ctx context.Context) error {
+	if atomic.LoadInt32(&s.started) == 0 {
+		return nil
+	}
+
+	done := make(chan struct{})
+
+	select {
+	case s.flushes <- done:
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *notifierSink) stats() NotifierStats {
+	return NotifierStats{
+		Queued:  atomic.LoadUint64(&s.queued),
+		Sent:    atomic.LoadUint64(&s.sent),
+		Dropped: atomic.LoadUint64(&s.dropped),
+		Failed:  atomic.LoadUint64(&s.failed),
+
+		Coalesced: atomic.LoadUint64(&s.coalescedCount),
+		Digested:  atomic.LoadUint64(&s.digested),
+	}
+}
+
+// Flush posts the messages queued for the notifiers, waiting for them to be
+// sent until ctx is done. Call it before exiting so no message is lost.
+func (l *Logger) Flush(ctx context.Context) error {
+	for _, s := range l.notifiers {
+		if err := s.flush(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Flush posts the messages the default Logger queued for the notifiers, see
+// Logger.Flush.
+func Flush(ctx context.Context) error {
+	return Default().Flush(ctx)
+}
+
+// NotifierStats returns the counters of the sinks of l posting through n,
+// which are shared with its children.
+func (l *Logger) NotifierStats(n Notifier) NotifierStats {
+	var stats NotifierStats
+	for _, s := range l.notifiers {
+		if s.notifier == n {
+			stats.add(s.stats())
+		}
+	}
+
+	return stats
+}
+
+// SlackStats returns the counters of the Slack sinks of l (see WithSlack),
+// which are shared with its children.
+func (l *Logger) SlackStats() SlackStats {
+	var stats SlackStats
+	for _, s := range l.notifiers {
+		if _, ok := s.notifier.(*slackWebhook); ok {
+			stats.add(s.stats())
+		}
+	}
+
+	return stats
+}
+
+func (s *NotifierStats) add(o NotifierStats) {
+	s.Queued += o.Queued
+	s.Sent += o.Sent
+	s.Dropped += o.Dropped
+	s.Failed += o.Failed
+	s.Coalesced += o.Coalesced
+	s.Digested += o.Digested
+}
@@ -0,0 +1,324 @@
+// Copyright (C) 2019 x6a
+//
+// pkg is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// pkg is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with pkg. If not, see <http://www.gnu.org/licenses/>.
+
+package xlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingNotifier records the batches it is asked to post. If block is not
+// nil, Notify signals started, if not signaled already, and waits for block
+// to be closed.
+type recordingNotifier struct {
+	mu      sync.Mutex
+	batches [][]*Message
+
+	started chan struct{}
+	block   chan struct{}
+}
+
+func (n *recordingNotifier) Notify(channel string, msgs []*Message) error {
+	if n.block != nil {
+		select {
+		case n.started <- struct{}{}:
+		default:
+		}
+		<-n.block
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.batches = append(n.batches, msgs)
+
+	return nil
+}
+
+func (n *recordingNotifier) texts() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var texts []string
+	for _, batch := range n.batches {
+		for _, m := range batch {
+			texts = append(texts, m.Text)
+		}
+	}
+
+	return texts
+}
+
+func newTestLogger(n Notifier, opt *NotifierOption) *Logger {
+	if len(opt.InfoChannel) == 0 {
+		opt.InfoChannel = "#test"
+	}
+
+	return New(INFO, "host", WithOutput(ioutil.Discard), WithNotifier(n, opt))
+}
+
+func flush(t *testing.T, l *Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := l.Flush(ctx); err != nil {
+		t.Fatalf("Flush() = %v", err)
+	}
+}
+
+func TestNotifierBatching(t *testing.T) {
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	n := &WebhookNotifier{}
+	l := newTestLogger(n, &NotifierOption{
+		Level:       INFO,
+		InfoChannel: srv.URL,
+		BatchSize:   3,
+		BatchWait:   time.Hour,
+	})
+	defer l.Close()
+
+	for i := 0; i < 7; i++ {
+		l.Infof("message %d", i)
+	}
+	flush(t, l)
+
+	var sizes []int
+	for _, body := range rec.requests() {
+		var data WebhookData
+		if err := json.Unmarshal(body, &data); err != nil {
+			t.Fatalf("json.Unmarshal() = %v", err)
+		}
+		sizes = append(sizes, len(data.Messages))
+	}
+	if fmt.Sprint(sizes) != "[3 3 1]" {
+		t.Errorf("got batches of %v messages, want [3 3 1]", sizes)
+	}
+
+	if stats := l.NotifierStats(n); stats.Queued != 7 || stats.Sent != 7 {
+		t.Errorf("got stats %+v, want 7 queued and sent", stats)
+	}
+}
+
+func TestNotifierBatchWait(t *testing.T) {
+	n := &recordingNotifier{}
+	l := newTestLogger(n, &NotifierOption{
+		Level:     INFO,
+		BatchWait: 10 * time.Millisecond,
+	})
+	defer l.Close()
+
+	l.Info("message")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(n.texts()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the batch wasn't posted after BatchWait")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func testDropPolicy(t *testing.T, policy DropPolicy, want string) {
+	n := &recordingNotifier{
+		started: make(chan struct{}, 1),
+		block:   make(chan struct{}),
+	}
+	l := newTestLogger(n, &NotifierOption{
+		Level:      INFO,
+		QueueSize:  2,
+		BatchSize:  1,
+		DropPolicy: policy,
+	})
+	defer l.Close()
+
+	// Wait for the first message to be posting, so the next ones fill the
+	// queue.
+	l.Info("0")
+	<-n.started
+
+	for i := 1; i <= 4; i++ {
+		l.Info(i)
+	}
+
+	close(n.block)
+	flush(t, l)
+
+	if got := strings.Join(n.texts(), " "); got != want {
+		t.Errorf("got messages %q, want %q", got, want)
+	}
+	if stats := l.NotifierStats(n); stats.Dropped != 2 {
+		t.Errorf("got %d dropped messages, want 2", stats.Dropped)
+	}
+}
+
+func TestNotifierDropNewest(t *testing.T) {
+	testDropPolicy(t, DropNewest, "0 1 2")
+}
+
+func TestNotifierDropOldest(t *testing.T) {
+	testDropPolicy(t, DropOldest, "0 3 4")
+}
+
+func TestNotifierCoalesce(t *testing.T) {
+	n := &recordingNotifier{}
+	l := newTestLogger(n, &NotifierOption{
+		Level:          INFO,
+		CoalesceWindow: time.Hour,
+	})
+	defer l.Close()
+
+	for i := 0; i < 5; i++ {
+		l.Info("same")
+	}
+	l.Info("other")
+	flush(t, l)
+
+	n.mu.Lock()
+	var got []string
+	for _, batch := range n.batches {
+		for _, m := range batch {
+			got = append(got, fmt.Sprintf("%s/%d", m.Text, m.Repeated))
+		}
+	}
+	n.mu.Unlock()
+
+	if want := "same/0 other/0 same/4"; strings.Join(got, " ") != want {
+		t.Errorf("got messages %q, want %q", strings.Join(got, " "), want)
+	}
+	if stats := l.NotifierStats(n); stats.Coalesced != 4 {
+		t.Errorf("got %d coalesced messages, want 4", stats.Coalesced)
+	}
+}
+
+func TestNotifierDigest(t *testing.T) {
+	n := &recordingNotifier{}
+	l := newTestLogger(n, &NotifierOption{
+		Level:        INFO,
+		RateLimit:    2,
+		RateInterval: time.Hour,
+	})
+	defer l.Close()
+
+	for i := 0; i < 5; i++ {
+		l.Infof("message %d", i)
+	}
+	flush(t, l)
+
+	n.mu.Lock()
+	var msgs []*Message
+	for _, batch := range n.batches {
+		msgs = append(msgs, batch...)
+	}
+	n.mu.Unlock()
+
+	if len(msgs) != 3 {
+		t.Fatalf("got %d messages, want 2 and the digest", len(msgs))
+	}
+
+	digest := msgs[2]
+	if digest.Digest != 3 {
+		t.Errorf("got a digest of %d messages, want 3", digest.Digest)
+	}
+	for i := 2; i < 5; i++ {
+		if !strings.Contains(digest.Text, fmt.Sprintf("message %d", i)) {
+			t.Errorf("digest %q doesn't quote message %d", digest.Text, i)
+		}
+	}
+	if stats := l.NotifierStats(n); stats.Digested != 3 {
+		t.Errorf("got %d digested messages, want 3", stats.Digested)
+	}
+}
+
+func TestNotifierClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	n := &recordingNotifier{}
+	for i := 0; i < 20; i++ {
+		l := newTestLogger(n, &NotifierOption{Level: INFO, BatchWait: time.Hour})
+		l.Info("message")
+		if err := l.Close(); err != nil {
+			t.Fatalf("Close() = %v", err)
+		}
+		// Logged after Close, dropped.
+		l.Info("message")
+	}
+
+	if got := len(n.texts()); got != 20 {
+		t.Errorf("got %d messages posted on Close, want 20", got)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d goroutines after Close, want %d", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNotifierCloseTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { notifyCloseTimeout = timeout }(notifyCloseTimeout)
+	notifyCloseTimeout = 50 * time.Millisecond
+
+	// A notifier never returning.
+	n := &recordingNotifier{
+		started: make(chan struct{}, 1),
+		block:   make(chan struct{}),
+	}
+	defer close(n.block)
+
+	l := newTestLogger(n, &NotifierOption{Level: INFO, BatchSize: 1})
+	l.Info("message")
+	<-n.started
+
+	start := time.Now()
+	if err := l.Close(); err == nil {
+		t.Error("Close() = nil, want the timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Close() took %v, want about %v", elapsed, notifyCloseTimeout)
+	}
+}
+
+func TestSetLoggerKeepsReplaced(t *testing.T) {
+	defer SetDefault(Default())
+
+	n := &recordingNotifier{}
+	SetLogger(INFO, "host", WithOutput(ioutil.Discard), WithNotifier(n, &NotifierOption{Level: INFO, InfoChannel: "#test"}))
+	child := Default().Child(WithHostID("child"))
+	defer child.Close()
+
+	SetLogger(INFO, "host", WithOutput(ioutil.Discard))
+
+	// The replaced Logger's sinks are still usable through its child.
+	child.Info("message")
+	flush(t, child)
+
+	if got := n.texts(); len(got) != 1 || got[0] != "message" {
+		t.Errorf("got messages %q, want the child's one", got)
+	}
+}
@@ -0,0 +1,311 @@
+// Copyright (C) 2019 x6a
+//
+// pkg is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// pkg is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with pkg. If not, see <http://www.gnu.org/licenses/>.
+
+package xlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"x6a.dev/pkg/errors"
+	"x6a.dev/pkg/internal/notify"
+)
+
+const (
+	contentTypeJSON = "application/json"
+
+	// Discord doesn't accept more embeds in a single message.
+	maxDiscordEmbeds = 10
+)
+
+func notifierName(n Notifier) string {
+	switch n.(type) {
+	case *slackWebhook:
+		return "Slack"
+	case *TeamsNotifier:
+		return "Teams"
+	case *DiscordNotifier:
+		return "Discord"
+	case *WebhookNotifier:
+		return "webhook"
+	case *SMTPNotifier:
+		return "email"
+	}
+
+	return fmt.Sprintf("%T", n)
+}
+
+func postJSON(client *http.Client, url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrapf(err, "function json.Marshal()")
+	}
+
+	return postWebhook(client, url, contentTypeJSON, body)
+}
+
+func postWebhook(client *http.Client, url, contentType string, body []byte) error {
+	if err := notify.PostWebhook(client, url, contentType, body); err != nil {
+		return errors.Wrapf(err, "function notify.PostWebhook()")
+	}
+
+	return nil
+}
+
+// TeamsNotifier posts the messages as a Microsoft Teams message card to the
+// incoming webhook URL configured as channel.
+type TeamsNotifier struct {
+	Client *http.Client
+}
+
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type teamsSection struct {
+	ActivityTitle string      `json:"activityTitle"`
+	Text          string      `json:"text"`
+	Facts         []teamsFact `json:"facts,omitempty"`
+}
+
+type teamsCard struct {
+	Type       string         `json:"@type"`
+	Context    string         `json:"@context"`
+	Summary    string         `json:"summary"`
+	ThemeColor string         `json:"themeColor"`
+	Sections   []teamsSection `json:"sections"`
+}
+
+func (n *TeamsNotifier) Notify(channel string, msgs []*Message) error {
+	card := &teamsCard{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    msgs[0].Title(),
+		ThemeColor: strings.TrimPrefix(msgs[len(msgs)-1].Color(), "#"),
+		Sections:   make([]teamsSection, len(msgs)),
+	}
+
+	for i, m := range msgs {
+		facts := []teamsFact{
+			{Name: "Priority", Value: string(m.Priority())},
+			{Name: "Severity", Value: m.Severity()},
+		}
+		for _, f := range messageFields(m) {
+			facts = append(facts, teamsFact{Name: f.Key, Value: f.String()})
+		}
+
+		card.Sections[i] = teamsSection{
+			ActivityTitle: m.Title(),
+			Text:          "<pre>" + html.EscapeString(m.Text) + "</pre>",
+			Facts:         facts,
+		}
+	}
+
+	return postJSON(n.Client, channel, card)
+}
+
+// DiscordNotifier posts the messages as Discord embeds to the webhook URL
+// configured as channel.
+type DiscordNotifier struct {
+	Client    *http.Client
+	Username  string
+	AvatarURL string
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Color       int            `json:"color"`
+	Timestamp   string         `json:"timestamp"`
+	Fields      []discordField `json:"fields,omitempty"`
+}
+
+type discordMessage struct {
+	Username  string         `json:"username,omitempty"`
+	AvatarURL string         `json:"avatar_url,omitempty"`
+	Embeds    []discordEmbed `json:"embeds"`
+}
+
+func (n *DiscordNotifier) Notify(channel string, msgs []*Message) error {
+	for len(msgs) > 0 {
+		batch := msgs
+		if len(batch) > maxDiscordEmbeds {
+			batch = batch[:maxDiscordEmbeds]
+		}
+		msgs = msgs[len(batch):]
+
+		dm := &discordMessage{
+			Username:  n.Username,
+			AvatarURL: n.AvatarURL,
+			Embeds:    make([]discordEmbed, len(batch)),
+		}
+
+		for i, m := range batch {
+			color, _ := strconv.ParseInt(strings.TrimPrefix(m.Color(), "#"), 16, 32)
+
+			fields := []discordField{
+				{Name: "Priority", Value: string(m.Priority()), Inline: true},
+				{Name: "Severity", Value: m.Severity(), Inline: true},
+			}
+			for _, f := range messageFields(m) {
+				fields = append(fields, discordField{Name: f.Key, Value: f.String(), Inline: true})
+			}
+
+			dm.Embeds[i] = discordEmbed{
+				Title:       m.Title(),
+				Description: "```" + m.Text + "```",
+				Color:       int(color),
+				Timestamp:   m.Timestamp.Format(time.RFC3339),
+				Fields:      fields,
+			}
+		}
+
+		if err := postJSON(n.Client, channel, dm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WebhookData is the data the WebhookNotifier body template is executed
+// with.
+type WebhookData struct {
+	Channel  string
+	Messages []*Message
+}
+
+// WebhookTemplate parses a WebhookNotifier body template. Besides the
+// text/template builtins, it provides the json function, which renders its
+// argument as JSON (e.g. {"text": {{json .Text}}}).
+func WebhookTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, errors.Wrapf(err, "function template.Parse()")
+	}
+
+	return tmpl, nil
+}
+
+// WebhookNotifier posts the messages to the webhook URL configured as
+// channel. The request body is the Body template (see WebhookTemplate)
+// executed with a WebhookData, or the JSON encoded WebhookData if Body is
+// nil.
+type WebhookNotifier struct {
+	Client      *http.Client
+	Body        *template.Template
+	ContentType string // application/json by default
+}
+
+func (n *WebhookNotifier) Notify(channel string, msgs []*Message) error {
+	data := &WebhookData{
+		Channel:  channel,
+		Messages: msgs,
+	}
+
+	if n.Body == nil {
+		return postJSON(n.Client, channel, data)
+	}
+
+	var body bytes.Buffer
+	if err := n.Body.Execute(&body, data); err != nil {
+		return errors.Wrapf(err, "function tmpl.Execute()")
+	}
+
+	contentType := n.ContentType
+	if len(contentType) == 0 {
+		contentType = contentTypeJSON
+	}
+
+	return postWebhook(n.Client, channel, contentType, body.Bytes())
+}
+
+// SMTPNotifier mails the messages to the comma separated addresses
+// configured as channel.
+type SMTPNotifier struct {
+	Addr    string // host:port of the SMTP server
+	Auth    smtp.Auth
+	From    string
+	Timeout time.Duration // of the whole exchange, 30 seconds by default
+}
+
+func (n *SMTPNotifier) Notify(channel string, msgs []*Message) error {
+	mail := &notify.Mail{
+		From:    n.From,
+		Subject: msgs[0].Title() + ": " + msgs[0].Text,
+	}
+
+	for _, rcpt := range strings.Split(channel, ",") {
+		if rcpt = strings.TrimSpace(rcpt); len(rcpt) > 0 {
+			mail.To = append(mail.To, rcpt)
+		}
+	}
+
+	if len(msgs) > 1 {
+		mail.Subject = fmt.Sprintf("[%s] %d messages @%s", msgs[0].Severity(), len(msgs), msgs[0].HostID)
+	}
+
+	var body strings.Builder
+	for _, m := range msgs {
+		fmt.Fprintf(&body, "%s\n", m.Title())
+		fmt.Fprintf(&body, "Priority: %s\n", m.Priority())
+		fmt.Fprintf(&body, "Severity: %s\n", m.Severity())
+		for _, f := range messageFields(m) {
+			fmt.Fprintf(&body, "%s: %s\n", f.Key, f.String())
+		}
+		fmt.Fprintf(&body, "\n%s\n\n", m.Text)
+	}
+	mail.Body = body.String()
+
+	if err := mail.Send(n.Addr, n.Auth, n.Timeout); err != nil {
+		return errors.Wrapf(err, "function notify.SendMail()")
+	}
+
+	return nil
+}
+
+// messageFields returns the fields of m, along with its repetition or digest
+// count.
+func messageFields(m *Message) []Field {
+	fields := m.Fields
+	if m.Repeated > 0 {
+		fields = append(fields[:len(fields):len(fields)], Int("Count", m.Repeated))
+	}
+	if m.Digest > 0 {
+		fields = append(fields[:len(fields):len(fields)], Int("Messages", m.Digest))
+	}
+
+	return fields
+}
@@ -0,0 +1,306 @@
+// Copyright (C) 2019 x6a
+//
+// pkg is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// pkg is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with pkg. If not, see <http://www.gnu.org/licenses/>.
+
+package xlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookRecorder is a webhook stand-in recording the request bodies.
+type webhookRecorder struct {
+	mu           sync.Mutex
+	bodies       [][]byte
+	contentTypes []string
+
+	// throttle answers the first requests with 429 Too Many Requests.
+	throttle int
+}
+
+func (r *webhookRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.throttle > 0 {
+		r.throttle--
+		w.Header().Set("Retry-After", "0.01")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	r.bodies = append(r.bodies, body)
+	r.contentTypes = append(r.contentTypes, req.Header.Get("Content-Type"))
+}
+
+func (r *webhookRecorder) requests() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([][]byte(nil), r.bodies...)
+}
+
+func testMessages(n int) []*Message {
+	msgs := make([]*Message, n)
+	for i := range msgs {
+		msgs[i] = &Message{
+			Level:     ERROR,
+			Timestamp: time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+			HostID:    "host",
+			Text:      fmt.Sprintf("message <%d>", i),
+			Fields:    []Field{String("user", "alice"), String("password", "abc def")},
+		}
+	}
+
+	return msgs
+}
+
+func TestTeamsNotifier(t *testing.T) {
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	n := &TeamsNotifier{}
+	if err := n.Notify(srv.URL, testMessages(2)); err != nil {
+		t.Fatalf("Notify() = %v", err)
+	}
+
+	reqs := rec.requests()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests, want 1", len(reqs))
+	}
+
+	var card teamsCard
+	if err := json.Unmarshal(reqs[0], &card); err != nil {
+		t.Fatalf("json.Unmarshal() = %v", err)
+	}
+	if card.Type != "MessageCard" || card.ThemeColor != "ff4444" {
+		t.Errorf("got card type %q, color %q", card.Type, card.ThemeColor)
+	}
+	if len(card.Sections) != 2 {
+		t.Fatalf("got %d sections, want 2", len(card.Sections))
+	}
+	if want := "<pre>message &lt;0&gt;</pre>"; card.Sections[0].Text != want {
+		t.Errorf("got text %q, want %q", card.Sections[0].Text, want)
+	}
+
+	facts := map[string]string{}
+	for _, f := range card.Sections[0].Facts {
+		facts[f.Name] = f.Value
+	}
+	if facts["Severity"] != "ERROR" || facts["user"] != "alice" || facts["password"] != "[REDACTED]" {
+		t.Errorf("got facts %v", facts)
+	}
+}
+
+func TestDiscordNotifier(t *testing.T) {
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	n := &DiscordNotifier{Username: "xlog"}
+	if err := n.Notify(srv.URL, testMessages(maxDiscordEmbeds+2)); err != nil {
+		t.Fatalf("Notify() = %v", err)
+	}
+
+	reqs := rec.requests()
+	if len(reqs) != 2 {
+		t.Fatalf("got %d requests, want 2", len(reqs))
+	}
+
+	for i, want := range []int{maxDiscordEmbeds, 2} {
+		var dm discordMessage
+		if err := json.Unmarshal(reqs[i], &dm); err != nil {
+			t.Fatalf("json.Unmarshal() = %v", err)
+		}
+		if dm.Username != "xlog" || len(dm.Embeds) != want {
+			t.Errorf("request %d: got username %q and %d embeds, want %d", i, dm.Username, len(dm.Embeds), want)
+		}
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	rec := &webhookRecorder{throttle: 2}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	tmpl, err := WebhookTemplate(`{"count":{{len .Messages}},"text":{{json (index .Messages 0).Text}},"fields":{{json (index .Messages 0).Fields}}}`)
+	if err != nil {
+		t.Fatalf("WebhookTemplate() = %v", err)
+	}
+
+	n := &WebhookNotifier{Body: tmpl, ContentType: "application/vnd.test+json"}
+	if err := n.Notify(srv.URL, testMessages(3)); err != nil {
+		t.Fatalf("Notify() = %v", err)
+	}
+
+	reqs := rec.requests()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests, want 1 after the throttled ones", len(reqs))
+	}
+	if want := `{"count":3,"text":"message \u003c0\u003e","fields":[{"Key":"user","Value":"alice"},{"Key":"password","Value":"[REDACTED]"}]}`; string(reqs[0]) != want {
+		t.Errorf("got body %s, want %s", reqs[0], want)
+	}
+	if rec.contentTypes[0] != "application/vnd.test+json" {
+		t.Errorf("got content type %q", rec.contentTypes[0])
+	}
+}
+
+func TestWebhookNotifierDefaultBody(t *testing.T) {
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	n := &WebhookNotifier{}
+	if err := n.Notify(srv.URL, testMessages(1)); err != nil {
+		t.Fatalf("Notify() = %v", err)
+	}
+
+	var data struct {
+		Channel  string
+		Messages []struct {
+			Text   string
+			Fields []struct {
+				Key   string
+				Value string
+			}
+		}
+	}
+	if err := json.Unmarshal(rec.requests()[0], &data); err != nil {
+		t.Fatalf("json.Unmarshal() = %v", err)
+	}
+	if data.Channel != srv.URL || len(data.Messages) != 1 || data.Messages[0].Text != "message <0>" {
+		t.Fatalf("got %+v", data)
+	}
+
+	fields := data.Messages[0].Fields
+	if len(fields) != 2 || fields[0].Value != "alice" || fields[1].Key != "password" || fields[1].Value != "[REDACTED]" {
+		t.Errorf("got fields %+v, want the password redacted", fields)
+	}
+	if rec.contentTypes[0] != contentTypeJSON {
+		t.Errorf("got content type %q", rec.contentTypes[0])
+	}
+}
+
+func TestWebhookNotifierError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	n := &WebhookNotifier{}
+	if err := n.Notify(srv.URL, testMessages(1)); err == nil {
+		t.Error("Notify() = nil, want the 400 Bad Request error")
+	}
+}
+
+// smtpServer is an SMTP stand-in returning the received mails.
+func smtpServer(t *testing.T) (string, <-chan string, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+
+	mails := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+
+				r := bufio.NewReader(conn)
+				fmt.Fprint(conn, "220 localhost\r\n")
+
+				var data *strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+
+					switch {
+					case data != nil && line == ".\r\n":
+						mails <- data.String()
+						data = nil
+						fmt.Fprint(conn, "250 OK\r\n")
+					case data != nil:
+						data.WriteString(line)
+					case strings.HasPrefix(line, "DATA"):
+						data = &strings.Builder{}
+						fmt.Fprint(conn, "354 Go ahead\r\n")
+					case strings.HasPrefix(line, "QUIT"):
+						fmt.Fprint(conn, "221 Bye\r\n")
+						return
+					default:
+						fmt.Fprint(conn, "250 OK\r\n")
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	return ln.Addr().String(), mails, func() { ln.Close() }
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	addr, mails, stop := smtpServer(t)
+	defer stop()
+
+	msgs := testMessages(1)
+	msgs[0].Text = "first line\r\nSubject: injected\rsecond line"
+
+	n := &SMTPNotifier{Addr: addr, From: "xlog@example.com"}
+	if err := n.Notify("ops@example.com, dev@example.com", msgs); err != nil {
+		t.Fatalf("Notify() = %v", err)
+	}
+
+	var mail string
+	select {
+	case mail = <-mails:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+	}
+
+	header := strings.SplitN(mail, "\r\n\r\n", 2)[0]
+	for _, want := range []string{
+		"To: ops@example.com, dev@example.com\r\n",
+		"Subject: [ERROR] 2019-01-02 03:04:05.000 @host: first line Subject: injected second line\r\n",
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("header %q doesn't contain %q", header, want)
+		}
+	}
+	if strings.Contains(header, "\r\nSubject: injected") {
+		t.Errorf("header %q has an injected field", header)
+	}
+	if !strings.Contains(mail, "password: [REDACTED]\r\n") {
+		t.Errorf("mail %q doesn't redact the password", mail)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright (C) 2019 x6a
+//
+// pkg is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// pkg is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with pkg. If not, see <http://www.gnu.org/licenses/>.
+
+package xlog
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// maxDigestLines is the number of messages quoted in a digest.
+const maxDigestLines = 10
+
+// minNotifyTick bounds the period the coalescing and rate windows are checked
+// at.
+const minNotifyTick = 10 * time.Millisecond
+
+type coalescedEntry struct {
+	first *notifyEntry
+	last  *notifyEntry
+	count int // repetitions of first held since it was posted
+	until time.Time
+}
+
+type levelRate struct {
+	end        time.Time
+	count      int
+	suppressed []*notifyEntry
+}
+
+func coalesceKey(m *Message) string {
+	return m.Channel + "\x00" + strconv.Itoa(int(m.Level)) + "\x00" + m.Text + "\x00" + fieldsText(m.Fields, false)
+}
+
+// accept applies the coalescing and the rate limit to e, returning the
+// entries to be posted.
+func (s *notifierSink) accept(e *notifyEntry) []*notifyEntry {
+	var out []*notifyEntry
+
+	if s.coalesceWindow > 0 {
+		key := coalesceKey(e.msg)
+		if c, ok := s.coalesced[key]; ok {
+			if e.msg.Timestamp.Before(c.until) {
+				c.last = e
+				c.count++
+				atomic.AddUint64(&s.coalescedCount, 1)
+				return nil
+			}
+			if c.count > 0 {
+				out = append(out, s.limit(c.summary())...)
+			}
+		}
+		s.coalesced[key] = &coalescedEntry{
+			first: e,
+			last:  e,
+			until: e.msg.Timestamp.Add(s.coalesceWindow),
+		}
+	}
+
+	return append(out, s.limit(e)...)
+}
+
+// limit applies the rate limit of e's level, returning the entries to be
+// posted: e, if it is within the limit, preceded by the digest of the
+// previous interval, if any.
+func (s *notifierSink) limit(e *notifyEntry) []*notifyEntry {
+	if s.rateLimit <= 0 {
+		return []*notifyEntry{e}
+	}
+
+	var out []*notifyEntry
+
+	now := time.Now()
+	r, ok := s.rates[e.msg.Level]
+	if !ok || !now.Before(r.end) {
+		if ok && len(r.suppressed) > 0 {
+			out = append(out, s.digest(r))
+		}
+		r = &levelRate{end: now.Add(s.rateInterval)}
+		s.rates[e.msg.Level] = r
+	}
+
+	r.count++
+	if r.count > s.rateLimit {
+		r.suppressed = append(r.suppressed, e)
+		atomic.AddUint64(&s.digested, 1)
+		return out
+	}
+
+	return append(out, e)
+}
+
+// expire returns the summaries of the coalescing windows and the digests of
+// the rate intervals ended at now, or of all of them.
+func (s *notifierSink) expire(now time.Time, all bool) []*notifyEntry {
+	var out []*notifyEntry
+
+	var ended []*coalescedEntry
+	for key, c := range s.coalesced {
+		if all || !now.Before(c.until) {
+			if c.count > 0 {
+				ended = append(ended, c)
+			}
+			delete(s.coalesced, key)
+		}
+	}
+	sort.Slice(ended, func(i, j int) bool {
+		return ended[i].first.msg.Timestamp.Before(ended[j].first.msg.Timestamp)
+	})
+	for _, c := range ended {
+		out = append(out, s.limit(c.summary())...)
+	}
+
+	for level := TRACE; level <= ALERT; level++ {
+		r, ok := s.rates[level]
+		if !ok || (!all && now.Before(r.end)) {
+			continue
+		}
+		if len(r.suppressed) > 0 {
+			out = append(out, s.digest(r))
+		}
+		delete(s.rates, level)
+	}
+
+	return out
+}
+
+func (s *notifierSink) tickPeriod() time.Duration {
+	var period time.Duration
+	if s.coalesceWindow > 0 {
+		period = s.coalesceWindow
+	}
+	if s.rateLimit > 0 && (period == 0 || s.rateInterval < period) {
+		period = s.rateInterval
+	}
+	if period == 0 {
+		return 0
+	}
+
+	period /= 2
+	if period < minNotifyTick {
+		period = minNotifyTick
+	}
+
+	return period
+}
+
+// summary returns the entry posting the count of the repetitions of the
+// coalesced message.
+func (c *coalescedEntry) summary() *notifyEntry {
+	msg := *c.last.msg
+	msg.Repeated = c.count
+
+	return &notifyEntry{
+		logger: c.last.logger,
+		msg:    &msg,
+	}
+}
+
+// digest returns the entry posting the messages suppressed by the rate limit.
+func (s *notifierSink) digest(r *levelRate) *notifyEntry {
+	last := r.suppressed[len(r.suppressed)-1]
+
+	lines := make([]string, 0, maxDigestLines+1)
+	for i, e := range r.suppressed {
+		if i == maxDigestLines {
+			lines = append(lines, "... and "+strconv.Itoa(len(r.suppressed)-maxDigestLines)+" more")
+			break
+		}
+		lines = append(lines, e.msg.Timestamp.Format(TIME_FORMAT)+" "+e.msg.Text)
+	}
+
+	return &notifyEntry{
+		logger: last.logger,
+		msg: &Message{
+			Level:     last.msg.Level,
+			Timestamp: time.Now(),
+			HostID:    last.msg.HostID,
+			Channel:   last.msg.Channel,
+			Text:      strings.Join(lines, "\n"),
+			Digest:    len(r.suppressed),
+		},
+	}
+}
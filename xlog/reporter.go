@@ -0,0 +1,85 @@
+// Copyright (C) 2019 x6a
+//
+// pkg is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// pkg is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with pkg. If not, see <http://www.gnu.org/licenses/>.
+
+package xlog
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"x6a.dev/pkg/errors"
+)
+
+var errorSeverityLevels = map[errors.Severity]LogLevel{
+	errors.SeverityTrace:    TRACE,
+	errors.SeverityDebug:    DEBUG,
+	errors.SeverityInfo:     INFO,
+	errors.SeverityWarning:  WARN,
+	errors.SeverityError:    ERROR,
+	errors.SeverityCritical: ALERT,
+	errors.SeverityFatal:    ALERT,
+	errors.SeverityPanic:    ALERT,
+}
+
+type errorReporter struct {
+	logger *Logger
+}
+
+// ErrorReporter returns an errors.Reporter writing through the default
+// Logger, to be installed with errors.SetDefaultReporter or
+// errors.SetReporter.
+func ErrorReporter() errors.Reporter {
+	return errorReporter{}
+}
+
+// ErrorReporter returns an errors.Reporter writing through l.
+func (l *Logger) ErrorReporter() errors.Reporter {
+	return errorReporter{logger: l}
+}
+
+func (r errorReporter) Report(e *errors.Error) {
+	l := r.logger
+	if l == nil {
+		l = Default()
+	}
+
+	level, ok := errorSeverityLevels[e.Error.Severity]
+	if !ok {
+		level = ERROR
+	}
+
+	attrs := make([]string, 0)
+	if len(e.Error.Code) > 0 {
+		attrs = append(attrs, "errorCode: "+e.Error.Code)
+	}
+	attrs = append(attrs,
+		"type: "+e.Error.Type,
+		"code: "+strconv.Itoa(e.Error.StatusCode),
+		"priority: "+string(e.Error.Priority),
+	)
+
+	ctxFields := e.Error.ContextFields()
+	keys := make([]string, 0, len(ctxFields))
+	for k := range ctxFields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		attrs = append(attrs, k+": "+ctxFields[k])
+	}
+
+	l.logf(level, "%s [%s]", e.Error.Message, strings.Join(attrs, ", "))
+}
@@ -17,38 +17,153 @@ package xlog
 
 import (
 	"encoding/json"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/nlopes/slack"
 	"x6a.dev/pkg/errors"
+	"x6a.dev/pkg/internal/notify"
 )
 
-func (l *logger) slackMsgTitle(level LogLevel, timestamp time.Time) string {
-	return "[" + l.severity(level) + "] " + timestamp.Format(TIME_FORMAT) + " @" + l.hostID
+const maxSlackRetries = 3
+
+// slackClient posts to the Slack webhooks, http.DefaultClient having no
+// timeout.
+var slackClient = &http.Client{Timeout: notify.WebhookTimeout}
+
+// slackWebhook is the Notifier of WithSlack, posting each batch as a message
+// with an attachment per log message.
+type slackWebhook struct {
+	webhook string
+	user    string
+	icon    string
+}
+
+func (n *slackWebhook) attachment(m *Message) slack.Attachment {
+	attachment := slack.Attachment{
+		Title:      m.Title(),
+		Text:       "```" + m.Text + "```",
+		Color:      m.Color(),
+		AuthorName: n.user,
+		AuthorIcon: n.icon,
+		Ts:         json.Number(strconv.Itoa(int(m.Timestamp.Unix()))),
+		Fields: []slack.AttachmentField{
+			{
+				Title: "Priority",
+				Value: string(m.Priority()),
+				Short: true,
+			},
+			{
+				Title: "Severity",
+				Value: m.Severity(),
+				Short: true,
+			},
+			{
+				Title: "Timestamp",
+				Value: m.Timestamp.Format(time.RFC3339),
+				Short: false,
+			},
+		},
+	}
+
+	for _, f := range messageFields(m) {
+		attachment.Fields = append(attachment.Fields, slack.AttachmentField{
+			Title: f.Key,
+			Value: f.String(),
+			Short: true,
+		})
+	}
+
+	return attachment
+}
+
+func (n *slackWebhook) Notify(channel string, msgs []*Message) error {
+	m := slack.WebhookMessage{
+		Username:    n.user,
+		IconURL:     n.icon,
+		Channel:     channel,
+		Attachments: make([]slack.Attachment, len(msgs)),
+		Parse:       "full",
+	}
+	for i, msg := range msgs {
+		m.Attachments[i] = n.attachment(msg)
+	}
+
+	if err := postSlackWebhook(n.webhook, &m); err != nil {
+		return errors.Wrapf(err, "function slack.PostWebhookCustomHTTP()")
+	}
+
+	return nil
+}
+
+// postSlackWebhook posts m, waiting and retrying as long as Slack rate limits
+// it with a Retry-After delay (up to notify.MaxRetryAfter).
+func postSlackWebhook(webhook string, m *slack.WebhookMessage) error {
+	for attempt := 1; ; attempt++ {
+		err := slack.PostWebhookCustomHTTP(webhook, slackClient, m)
+
+		rle, ok := err.(*slack.RateLimitedError)
+		if !ok || attempt > maxSlackRetries {
+			return err
+		}
+
+		time.Sleep(notify.CapRetryAfter(rle.RetryAfter))
+	}
+}
+
+type slackNotifier struct {
+	webhook string
+	user    string
+	icon    string
 }
 
-func (l *logger) slackLog(level LogLevel, timestamp time.Time, msg string) error {
-	if len(l.slackLogger.channels[level]) == 0 {
-		return nil
+// SlackNotifier returns an errors.Notifier posting to the channel of
+// "slack:#channel" targets through the given webhook. Register it with
+// errors.RegisterNotifier for the slack scheme.
+func SlackNotifier(webhook, user, icon string) errors.Notifier {
+	return &slackNotifier{
+		webhook: webhook,
+		user:    user,
+		icon:    icon,
+	}
+}
+
+func (n *slackNotifier) Notify(to string, e *errors.Error) error {
+	timestamp := time.Now()
+
+	level, ok := errorSeverityLevels[e.Error.Severity]
+	if !ok {
+		level = ERROR
 	}
 
 	attachment := slack.Attachment{
-		Title:      l.slackMsgTitle(level, timestamp),
-		Text:       "```" + msg + "```",
-		Color:      l.slackLogger.colors[level],
-		AuthorName: l.slackLogger.user,
-		AuthorIcon: l.slackLogger.icon,
+		Title:      "[" + string(e.Error.Severity) + "] " + timestamp.Format(TIME_FORMAT) + " @" + Default().HostID(),
+		Text:       "```" + e.Error.Message + "```",
+		Color:      slackColors[level],
+		AuthorName: n.user,
+		AuthorIcon: n.icon,
 		Ts:         json.Number(strconv.Itoa(int(timestamp.Unix()))),
 		Fields: []slack.AttachmentField{
 			{
 				Title: "Priority",
-				Value: string(l.priority(level)),
+				Value: string(e.Error.Priority),
 				Short: true,
 			},
 			{
 				Title: "Severity",
-				Value: l.severity(level),
+				Value: string(e.Error.Severity),
+				Short: true,
+			},
+			{
+				Title: "Type",
+				Value: e.Error.Type,
+				Short: true,
+			},
+			{
+				Title: "Status Code",
+				Value: strconv.Itoa(e.Error.StatusCode),
 				Short: true,
 			},
 			{
@@ -59,17 +174,24 @@ func (l *logger) slackLog(level LogLevel, timestamp time.Time, msg string) error
 		},
 	}
 
+	for k, v := range e.Error.ContextFields() {
+		attachment.Fields = append(attachment.Fields, slack.AttachmentField{
+			Title: k,
+			Value: v,
+			Short: true,
+		})
+	}
+
 	m := slack.WebhookMessage{
-		Username: l.slackLogger.user,
-		IconURL:  l.slackLogger.icon,
-		Channel:  l.slackLogger.channels[level],
-		// Text: msg,
+		Username:    n.user,
+		IconURL:     n.icon,
+		Channel:     strings.TrimPrefix(to, "slack:"),
 		Attachments: []slack.Attachment{attachment},
 		Parse:       "full",
 	}
 
-	if err := slack.PostWebhook(l.slackLogger.webhook, &m); err != nil {
-		return errors.Wrapf(err, "[%v] function slack.PostWebhook()", errors.Trace())
+	if err := slack.PostWebhookCustomHTTP(n.webhook, slackClient, &m); err != nil {
+		return errors.Wrapf(err, "function slack.PostWebhookCustomHTTP()")
 	}
 
 	return nil
@@ -0,0 +1,316 @@
+// Copyright (C) 2019 x6a
+//
+// pkg is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// pkg is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with pkg. If not, see <http://www.gnu.org/licenses/>.
+
+package xlog
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"x6a.dev/pkg/errors"
+)
+
+// SyslogFormat is the syslog message format.
+type SyslogFormat int
+
+const (
+	// SyslogAuto uses RFC 5424 over the network and RFC 3164 on the local
+	// socket, the format every local syslog daemon understands.
+	SyslogAuto SyslogFormat = iota
+	SyslogRFC5424
+	SyslogRFC3164
+)
+
+const (
+	syslogFacilityUser = 1
+
+	// syslogSDID is the structured data ID of the fields, using the
+	// enterprise number reserved for documentation (RFC 5612).
+	syslogSDID = "fields@32473"
+
+	syslogDialTimeout  = 5 * time.Second
+	syslogWriteTimeout = 5 * time.Second
+)
+
+// syslogSocketPaths are the usual local syslog sockets.
+var syslogSocketPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+var syslogSeverities = map[LogLevel]int{
+	TRACE: 7, // debug
+	DEBUG: 7, // debug
+	INFO:  6, // informational
+	WARN:  4, // warning
+	ERROR: 3, // error
+	ALERT: 1, // alert
+}
+
+type SyslogOption struct {
+	Level LogLevel
+
+	// Network is "udp", "tcp" or "tls" to send the messages to the Addr
+	// server, or empty to use the local syslog socket (Addr, if not empty,
+	// or the first of /dev/log, /var/run/syslog and /var/run/log found).
+	Network   string
+	Addr      string
+	TLSConfig *tls.Config
+
+	// Facility is the syslog facility code, 1 (user-level) by default.
+	Facility int
+	// AppName identifies the program, its executable name by default.
+	AppName string
+	Format  SyslogFormat
+}
+
+type syslogSink struct {
+	opt SyslogOption
+
+	mu       sync.Mutex
+	conn     net.Conn
+	framed   bool // octet counting framing (RFC 6587) for stream transports
+	local    bool
+	hostname string
+	closed   bool
+}
+
+// WithSyslog sends the messages of opt.Level and above to a syslog server.
+// The connection is established on the first message and reestablished
+// whenever it fails.
+func WithSyslog(opt *SyslogOption) *LogOption {
+	sink := &syslogSink{
+		opt: *opt,
+	}
+
+	if sink.opt.Facility == 0 {
+		sink.opt.Facility = syslogFacilityUser
+	}
+	if len(sink.opt.AppName) == 0 {
+		sink.opt.AppName = filepath.Base(os.Args[0])
+	}
+
+	return &LogOption{
+		key:   logOptionSyslog,
+		value: sink,
+	}
+}
+
+func (s *syslogSink) connect() error {
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	switch s.opt.Network {
+	case "udp", "udp4", "udp6":
+		conn, err = net.DialTimeout(s.opt.Network, s.opt.Addr, syslogDialTimeout)
+		s.framed = false
+	case "tcp", "tcp4", "tcp6":
+		conn, err = net.DialTimeout(s.opt.Network, s.opt.Addr, syslogDialTimeout)
+		s.framed = true
+	case "tls":
+		dialer := &net.Dialer{Timeout: syslogDialTimeout}
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.opt.Addr, s.opt.TLSConfig)
+		s.framed = true
+	case "":
+		conn, err = dialLocalSyslog(s.opt.Addr)
+		s.framed = false
+		s.local = true
+	default:
+		return errors.Errorf("unsupported syslog network %q", s.opt.Network)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "function net.Dial(): syslog %v %v", s.opt.Network, s.opt.Addr)
+	}
+
+	s.conn = conn
+
+	return nil
+}
+
+func dialLocalSyslog(addr string) (net.Conn, error) {
+	paths := syslogSocketPaths
+	if len(addr) > 0 {
+		paths = []string{addr}
+	}
+
+	var err error
+	for _, path := range paths {
+		for _, network := range []string{"unixgram", "unix"} {
+			var conn net.Conn
+			if conn, err = net.DialTimeout(network, path, syslogDialTimeout); err == nil {
+				return conn, nil
+			}
+		}
+	}
+
+	return nil, err
+}
+
+// write sends the message, reconnecting and retrying once if the connection
+// failed.
+func (s *syslogSink) write(level LogLevel, timestamp time.Time, hostID, msg string, fields []Field) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errors.Wrapf(os.ErrClosed, "function conn.Write(): syslog")
+	}
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			if err = s.connect(); err != nil {
+				return err
+			}
+		}
+
+		line := s.format(level, timestamp, hostID, msg, fields)
+		if s.framed {
+			line = strings.TrimSuffix(line, "\n")
+			line = strconv.Itoa(len(line)) + " " + line
+		}
+
+		// A stalled server mustn't block the logging goroutines.
+		if err = s.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout)); err == nil {
+			if _, err = s.conn.Write([]byte(line)); err == nil {
+				return nil
+			}
+		}
+
+		s.conn.Close()
+		s.conn = nil
+	}
+
+	return errors.Wrapf(err, "function conn.Write(): syslog")
+}
+
+func (s *syslogSink) format(level LogLevel, timestamp time.Time, hostID, msg string, fields []Field) string {
+	pri := "<" + strconv.Itoa(s.opt.Facility*8+syslogSeverities[level]) + ">"
+	pid := strconv.Itoa(os.Getpid())
+
+	hostname := hostID
+	if len(hostname) == 0 {
+		hostname = s.localHostname()
+	}
+
+	if s.opt.Format == SyslogRFC3164 || (s.opt.Format == SyslogAuto && s.local) {
+		if len(fields) > 0 {
+			msg += " " + fieldsText(fields, false)
+		}
+
+		// The local daemon adds the hostname itself.
+		header := pri + timestamp.Format(time.Stamp) + " "
+		if !s.local {
+			header += strings.Replace(hostname, " ", "_", -1) + " "
+		}
+
+		return header + s.opt.AppName + "[" + pid + "]: " + msg + "\n"
+	}
+
+	return pri + "1 " +
+		timestamp.Format("2006-01-02T15:04:05.000000Z07:00") + " " +
+		syslogHeaderField(hostname, 255) + " " +
+		syslogHeaderField(s.opt.AppName, 48) + " " +
+		syslogHeaderField(pid, 128) + " - " +
+		syslogStructuredData(fields) + " " +
+		msg + "\n"
+}
+
+func (s *syslogSink) localHostname() string {
+	if len(s.hostname) == 0 {
+		if h, err := os.Hostname(); err == nil {
+			s.hostname = h
+		} else {
+			s.hostname = "-"
+		}
+	}
+
+	return s.hostname
+}
+
+// syslogHeaderField returns v limited to the printable US-ASCII characters
+// and the given length, or the NILVALUE if empty.
+func syslogHeaderField(v string, max int) string {
+	v = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, v)
+
+	if len(v) == 0 {
+		return "-"
+	}
+	if len(v) > max {
+		v = v[:max]
+	}
+
+	return v
+}
+
+// syslogStructuredData renders the fields as an RFC 5424 SD-ELEMENT.
+func syslogStructuredData(fields []Field) string {
+	if len(fields) == 0 {
+		return "-"
+	}
+
+	var b strings.Builder
+	b.WriteString("[" + syslogSDID)
+	for _, f := range fields {
+		name := strings.Map(func(r rune) rune {
+			if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+				return -1
+			}
+			return r
+		}, f.Key)
+		if len(name) == 0 {
+			continue
+		}
+		if len(name) > 32 {
+			name = name[:32]
+		}
+
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(f.String())
+		b.WriteString(" " + name + `="` + value + `"`)
+	}
+	b.WriteString("]")
+
+	return b.String()
+}
+
+// Close closes the connection, failing the writes made afterwards.
+func (s *syslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Close()
+	s.conn = nil
+	if err != nil {
+		return errors.Wrapf(err, "function conn.Close(): syslog")
+	}
+
+	return nil
+}
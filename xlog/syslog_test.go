@@ -0,0 +1,88 @@
+// Copyright (C) 2019 x6a
+//
+// pkg is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// pkg is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with pkg. If not, see <http://www.gnu.org/licenses/>.
+
+package xlog
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSyslogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	defer ln.Close()
+
+	var accepted int32
+	lines := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&accepted, 1)
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					lines <- line
+				}
+			}(conn)
+		}
+	}()
+
+	s := WithSyslog(&SyslogOption{Network: "tcp", Addr: ln.Addr().String(), AppName: "app"}).value.(*syslogSink)
+
+	// Octet counting framing has no trailing newline: send a second message
+	// to delimit the first one.
+	for _, msg := range []string{"first\n", "second"} {
+		if err := s.write(ERROR, time.Now(), "host", msg, []Field{String("user", "alice")}); err != nil {
+			t.Fatalf("write() = %v", err)
+		}
+	}
+
+	select {
+	case line := <-lines:
+		frame := strings.SplitN(strings.TrimSuffix(line, "\n"), " ", 2)
+		if len(frame) != 2 || !strings.HasPrefix(frame[1], "<11>1 ") || !strings.Contains(frame[1], ` app `) || !strings.Contains(frame[1], `[fields@32473 user="alice"] first`) {
+			t.Errorf("got frame %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+	if err := s.write(ERROR, time.Now(), "host", "after close", nil); err == nil {
+		t.Error("write() = nil after Close, want an error")
+	}
+	if n := atomic.LoadInt32(&accepted); n != 1 {
+		t.Errorf("got %d connections, want 1 without redialing after Close", n)
+	}
+}